@@ -10,11 +10,23 @@ import (
 	"github.com/TenacityLabs/retrospect-backend/config"
 	"github.com/TenacityLabs/retrospect-backend/services/audio"
 	"github.com/TenacityLabs/retrospect-backend/services/capsule"
+	"github.com/TenacityLabs/retrospect-backend/services/capsuleTemplate"
+	"github.com/TenacityLabs/retrospect-backend/services/clock"
+	"github.com/TenacityLabs/retrospect-backend/services/device"
 	"github.com/TenacityLabs/retrospect-backend/services/doodle"
 	"github.com/TenacityLabs/retrospect-backend/services/file"
+	"github.com/TenacityLabs/retrospect-backend/services/gift"
+	"github.com/TenacityLabs/retrospect-backend/services/invite"
+	"github.com/TenacityLabs/retrospect-backend/services/joinRequest"
+	"github.com/TenacityLabs/retrospect-backend/services/letter"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
 	"github.com/TenacityLabs/retrospect-backend/services/miscFile"
+	"github.com/TenacityLabs/retrospect-backend/services/notificationPreference"
+	"github.com/TenacityLabs/retrospect-backend/services/outbox"
 	"github.com/TenacityLabs/retrospect-backend/services/photo"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
 	"github.com/TenacityLabs/retrospect-backend/services/questionAnswer"
+	"github.com/TenacityLabs/retrospect-backend/services/scheduler"
 	"github.com/TenacityLabs/retrospect-backend/services/song"
 	"github.com/TenacityLabs/retrospect-backend/services/user"
 	"github.com/TenacityLabs/retrospect-backend/services/writing"
@@ -48,34 +60,74 @@ func (server *APIServer) Run() error {
 	router := mux.NewRouter()
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
+	// everything queues mail and pushes in the outbox, only the worker talks to the mail and push backends
+	outboxStore := outbox.NewOutboxStore(server.db)
+	deviceStore := device.NewDeviceStore(server.db)
+	preferenceStore := notificationPreference.NewNotificationPreferenceStore(server.db)
+	mailer := outbox.NewMailer(outboxStore)
+	pusher := outbox.NewPusher(outboxStore)
+	outbox.NewWorker(outboxStore, deviceStore, mail.NewMailer(), push.NewPushSender()).Start(ctx)
+
+	// capsule lifecycle times can be faked in development to walk a capsule through sealing and opening
+	capsuleClock := clock.NewClock()
+
 	userStore := user.NewUserStore(server.db)
-	capsuleStore := capsule.NewCapsuleStore(server.db)
+	capsuleStore := capsule.NewCapsuleStore(server.db, outboxStore, capsuleClock)
 	fileStore := file.NewFileStore(bucket)
+	inviteStore := invite.NewInviteStore(server.db, capsuleClock)
+	giftStore := gift.NewGiftStore(server.db, outboxStore, capsuleClock)
+	templateStore := capsuleTemplate.NewCapsuleTemplateStore(server.db)
+	joinRequestStore := joinRequest.NewJoinRequestStore(server.db, capsuleClock)
 
 	songStore := song.NewSongStore(server.db)
 	questionAnswerStore := questionAnswer.NewQuestionAnswerStore(server.db)
 	writingStore := writing.NewWritingStore(server.db)
+	letterStore := letter.NewLetterStore(server.db)
 	photoStore := photo.NewPhotoStore(server.db)
 	audioStore := audio.NewAudioStore(server.db)
 	doodleStore := doodle.NewDoodleStore(server.db)
 	miscFileStore := miscFile.NewMiscFileStore(server.db)
 
-	userHandler := user.NewHandler(userStore)
+	userHandler := user.NewHandler(userStore, capsuleStore, inviteStore, giftStore, fileStore, mailer)
 	userHandler.RegisterRoutes(subrouter)
 	capsuleHandler := capsule.NewHandler(
 		capsuleStore,
 		userStore,
 		fileStore,
+		joinRequestStore,
+		mailer,
+		pusher,
+		preferenceStore,
+		capsuleClock,
+		giftStore,
+		templateStore,
 
 		songStore,
 		questionAnswerStore,
 		writingStore,
+		letterStore,
 		photoStore,
 		audioStore,
 		doodleStore,
 		miscFileStore,
 	)
 	capsuleHandler.RegisterRoutes(subrouter)
+	templateHandler := capsuleTemplate.NewHandler(templateStore, userStore)
+	templateHandler.RegisterRoutes(subrouter)
+	inviteHandler := invite.NewHandler(inviteStore, capsuleStore, userStore, mailer, pusher, preferenceStore)
+	inviteHandler.RegisterRoutes(subrouter)
+	joinRequestHandler := joinRequest.NewHandler(joinRequestStore, capsuleStore, userStore, mailer, preferenceStore)
+	joinRequestHandler.RegisterRoutes(subrouter)
+	giftHandler := gift.NewHandler(giftStore, capsuleStore, userStore)
+	giftHandler.RegisterRoutes(subrouter)
+	outboxHandler := outbox.NewHandler(outboxStore)
+	outboxHandler.RegisterRoutes(subrouter)
+	deviceHandler := device.NewHandler(deviceStore, userStore)
+	deviceHandler.RegisterRoutes(subrouter)
+	preferenceHandler := notificationPreference.NewHandler(preferenceStore, userStore)
+	preferenceHandler.RegisterRoutes(subrouter)
+	clockHandler := clock.NewHandler(capsuleClock)
+	clockHandler.RegisterRoutes(subrouter)
 	fileHandler := file.NewHandler(userStore, fileStore)
 	fileHandler.RegisterRoutes(subrouter)
 
@@ -85,6 +137,8 @@ func (server *APIServer) Run() error {
 	questionAnswerHanlder.RegisterRoutes(subrouter)
 	writingHandler := writing.NewHandler(capsuleStore, userStore, writingStore)
 	writingHandler.RegisterRoutes(subrouter)
+	letterHandler := letter.NewHandler(capsuleStore, userStore, letterStore)
+	letterHandler.RegisterRoutes(subrouter)
 	photoHandler := photo.NewHandler(capsuleStore, userStore, fileStore, photoStore)
 	photoHandler.RegisterRoutes(subrouter)
 	audioHandler := audio.NewHandler(capsuleStore, userStore, fileStore, audioStore)
@@ -94,6 +148,9 @@ func (server *APIServer) Run() error {
 	miscFileHandler := miscFile.NewHandler(capsuleStore, userStore, fileStore, miscFileStore)
 	miscFileHandler.RegisterRoutes(subrouter)
 
+	capsuleScheduler := scheduler.NewScheduler(server.db, capsuleStore, giftStore)
+	capsuleScheduler.Start(ctx)
+
 	// TODO: limit origins for prod
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"log"
+	_ "time/tzdata" // capsule timezones shouldn't depend on the host having a zoneinfo database
 
 	"github.com/TenacityLabs/retrospect-backend/cmd/api"
 	"github.com/TenacityLabs/retrospect-backend/config"
@@ -0,0 +1,58 @@
+ALTER TABLE capsules
+  DROP COLUMN `memberLimit`,
+  ADD COLUMN `capsuleMember1Id` INT UNSIGNED AFTER `capsuleOwnerId`,
+  ADD COLUMN `capsuleMember2Id` INT UNSIGNED AFTER `capsuleMember1Id`,
+  ADD COLUMN `capsuleMember3Id` INT UNSIGNED AFTER `capsuleMember2Id`,
+  ADD COLUMN `capsuleMember4Id` INT UNSIGNED AFTER `capsuleMember3Id`,
+  ADD COLUMN `capsuleMember5Id` INT UNSIGNED AFTER `capsuleMember4Id`,
+  ADD COLUMN `capsuleMember1Sealed` BOOLEAN NOT NULL DEFAULT FALSE AFTER `capsuleMember5Id`,
+  ADD COLUMN `capsuleMember2Sealed` BOOLEAN NOT NULL DEFAULT FALSE AFTER `capsuleMember1Sealed`,
+  ADD COLUMN `capsuleMember3Sealed` BOOLEAN NOT NULL DEFAULT FALSE AFTER `capsuleMember2Sealed`,
+  ADD COLUMN `capsuleMember4Sealed` BOOLEAN NOT NULL DEFAULT FALSE AFTER `capsuleMember3Sealed`,
+  ADD COLUMN `capsuleMember5Sealed` BOOLEAN NOT NULL DEFAULT FALSE AFTER `capsuleMember4Sealed`,
+  ADD FOREIGN KEY (`capsuleMember1Id`) REFERENCES users(`id`),
+  ADD FOREIGN KEY (`capsuleMember2Id`) REFERENCES users(`id`),
+  ADD FOREIGN KEY (`capsuleMember3Id`) REFERENCES users(`id`);
+
+-- only the first five members (by join order) fit back into the fixed columns
+UPDATE capsules c
+JOIN (
+  SELECT capsuleId, userId, sealedAt, ROW_NUMBER() OVER (PARTITION BY capsuleId ORDER BY joinedAt, id) AS slot
+  FROM capsuleMembers
+  WHERE role != 'owner'
+) m ON m.capsuleId = c.id AND m.slot = 1
+SET c.capsuleMember1Id = m.userId, c.capsuleMember1Sealed = m.sealedAt IS NOT NULL;
+
+UPDATE capsules c
+JOIN (
+  SELECT capsuleId, userId, sealedAt, ROW_NUMBER() OVER (PARTITION BY capsuleId ORDER BY joinedAt, id) AS slot
+  FROM capsuleMembers
+  WHERE role != 'owner'
+) m ON m.capsuleId = c.id AND m.slot = 2
+SET c.capsuleMember2Id = m.userId, c.capsuleMember2Sealed = m.sealedAt IS NOT NULL;
+
+UPDATE capsules c
+JOIN (
+  SELECT capsuleId, userId, sealedAt, ROW_NUMBER() OVER (PARTITION BY capsuleId ORDER BY joinedAt, id) AS slot
+  FROM capsuleMembers
+  WHERE role != 'owner'
+) m ON m.capsuleId = c.id AND m.slot = 3
+SET c.capsuleMember3Id = m.userId, c.capsuleMember3Sealed = m.sealedAt IS NOT NULL;
+
+UPDATE capsules c
+JOIN (
+  SELECT capsuleId, userId, sealedAt, ROW_NUMBER() OVER (PARTITION BY capsuleId ORDER BY joinedAt, id) AS slot
+  FROM capsuleMembers
+  WHERE role != 'owner'
+) m ON m.capsuleId = c.id AND m.slot = 4
+SET c.capsuleMember4Id = m.userId, c.capsuleMember4Sealed = m.sealedAt IS NOT NULL;
+
+UPDATE capsules c
+JOIN (
+  SELECT capsuleId, userId, sealedAt, ROW_NUMBER() OVER (PARTITION BY capsuleId ORDER BY joinedAt, id) AS slot
+  FROM capsuleMembers
+  WHERE role != 'owner'
+) m ON m.capsuleId = c.id AND m.slot = 5
+SET c.capsuleMember5Id = m.userId, c.capsuleMember5Sealed = m.sealedAt IS NOT NULL;
+
+DROP TABLE IF EXISTS capsuleMembers;
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS capsuleMembers (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `userId` INT UNSIGNED NOT NULL,
+
+  `role` ENUM('owner', 'member') NOT NULL DEFAULT 'member',
+  `sealedAt` TIMESTAMP NULL, -- when the member agreed to seal, NULL if they haven't yet
+  `joinedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `capsuleUser` (`capsuleId`, `userId`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+-- backfill owners and members from the fixed member columns
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleOwnerId, 'owner', IF(sealed = 'preseal', NULL, createdAt), createdAt FROM capsules;
+
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleMember1Id, 'member', IF(capsuleMember1Sealed, createdAt, NULL), createdAt FROM capsules WHERE capsuleMember1Id IS NOT NULL;
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleMember2Id, 'member', IF(capsuleMember2Sealed, createdAt, NULL), createdAt FROM capsules WHERE capsuleMember2Id IS NOT NULL;
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleMember3Id, 'member', IF(capsuleMember3Sealed, createdAt, NULL), createdAt FROM capsules WHERE capsuleMember3Id IS NOT NULL;
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleMember4Id, 'member', IF(capsuleMember4Sealed, createdAt, NULL), createdAt FROM capsules WHERE capsuleMember4Id IS NOT NULL;
+INSERT INTO capsuleMembers (capsuleId, userId, role, sealedAt, joinedAt)
+SELECT id, capsuleMember5Id, 'member', IF(capsuleMember5Sealed, createdAt, NULL), createdAt FROM capsules WHERE capsuleMember5Id IS NOT NULL;
+
+ALTER TABLE capsules
+  DROP FOREIGN KEY capsules_ibfk_2,
+  DROP FOREIGN KEY capsules_ibfk_3,
+  DROP FOREIGN KEY capsules_ibfk_4;
+
+ALTER TABLE capsules
+  DROP COLUMN `capsuleMember1Id`,
+  DROP COLUMN `capsuleMember2Id`,
+  DROP COLUMN `capsuleMember3Id`,
+  DROP COLUMN `capsuleMember4Id`,
+  DROP COLUMN `capsuleMember5Id`,
+  DROP COLUMN `capsuleMember1Sealed`,
+  DROP COLUMN `capsuleMember2Sealed`,
+  DROP COLUMN `capsuleMember3Sealed`,
+  DROP COLUMN `capsuleMember4Sealed`,
+  DROP COLUMN `capsuleMember5Sealed`,
+  ADD COLUMN `memberLimit` INT UNSIGNED NOT NULL DEFAULT 10; -- maximum number of users (including the owner) in the capsule
@@ -0,0 +1,5 @@
+ALTER TABLE capsuleMembers MODIFY COLUMN `role` ENUM('owner', 'member', 'editor', 'contributor', 'viewer') NOT NULL DEFAULT 'member';
+
+UPDATE capsuleMembers SET role = 'member' WHERE role != 'owner';
+
+ALTER TABLE capsuleMembers MODIFY COLUMN `role` ENUM('owner', 'member') NOT NULL DEFAULT 'member';
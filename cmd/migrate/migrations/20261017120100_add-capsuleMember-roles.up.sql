@@ -0,0 +1,6 @@
+ALTER TABLE capsuleMembers MODIFY COLUMN `role` ENUM('owner', 'member', 'editor', 'contributor', 'viewer') NOT NULL DEFAULT 'contributor';
+
+-- existing members could always add content, so they become contributors
+UPDATE capsuleMembers SET role = 'contributor' WHERE role = 'member';
+
+ALTER TABLE capsuleMembers MODIFY COLUMN `role` ENUM('owner', 'editor', 'contributor', 'viewer') NOT NULL DEFAULT 'contributor';
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS invites (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `inviterId` INT UNSIGNED NOT NULL,
+
+  -- invitees are identified by email or phone so that they can be invited before registering
+  `email` VARCHAR(255),
+  `phone` VARCHAR(10),
+
+  `status` ENUM('pending', 'accepted', 'declined', 'expired') NOT NULL DEFAULT 'pending',
+  `expiresAt` TIMESTAMP NOT NULL,
+  `respondedAt` TIMESTAMP NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `email` (`email`),
+  KEY `phone` (`phone`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`inviterId`) REFERENCES users(`id`)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS joinRequests (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `userId` INT UNSIGNED NOT NULL,
+
+  `status` ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+  `respondedAt` TIMESTAMP NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
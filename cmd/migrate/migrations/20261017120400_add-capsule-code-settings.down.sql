@@ -0,0 +1,5 @@
+ALTER TABLE capsules
+  DROP COLUMN `codeExpiresAt`,
+  DROP COLUMN `codeMaxUses`,
+  DROP COLUMN `codeUses`,
+  DROP COLUMN `codeRevoked`;
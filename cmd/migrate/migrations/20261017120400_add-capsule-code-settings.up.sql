@@ -0,0 +1,5 @@
+ALTER TABLE capsules
+  ADD COLUMN `codeExpiresAt` TIMESTAMP NULL, -- code can't be used to join after this time, NULL if it never expires
+  ADD COLUMN `codeMaxUses` INT UNSIGNED, -- number of times the code can be used to join, NULL if unlimited
+  ADD COLUMN `codeUses` INT UNSIGNED NOT NULL DEFAULT 0,
+  ADD COLUMN `codeRevoked` BOOLEAN NOT NULL DEFAULT FALSE; -- whether the owner disabled joining via code
@@ -0,0 +1,10 @@
+ALTER TABLE capsules ADD COLUMN `emailSent` BOOLEAN NOT NULL DEFAULT FALSE AFTER `dateToOpen`;
+
+UPDATE capsules c
+SET c.emailSent = TRUE
+WHERE EXISTS (
+  SELECT 1 FROM capsuleDeliveries d
+  WHERE d.capsuleId = c.id AND d.kind = 'capsule-ready' AND d.status = 'sent'
+);
+
+DROP TABLE IF EXISTS capsuleDeliveries;
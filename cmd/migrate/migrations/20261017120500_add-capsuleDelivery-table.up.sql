@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS capsuleDeliveries (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `userId` INT UNSIGNED NOT NULL,
+  `kind` VARCHAR(32) NOT NULL, -- which mail template was delivered, eg. capsule-ready
+
+  `status` ENUM('pending', 'sent', 'failed') NOT NULL DEFAULT 'pending',
+  `attempts` INT UNSIGNED NOT NULL DEFAULT 0,
+  `lastError` TEXT,
+  `sentAt` TIMESTAMP NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `capsuleUserKind` (`capsuleId`, `userId`, `kind`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+-- only owners were mailed before, so members of those capsules aren't sent a late reminder either
+INSERT INTO capsuleDeliveries (capsuleId, userId, kind, status, attempts, sentAt)
+SELECT c.id, m.userId, 'capsule-ready', 'sent', IF(m.role = 'owner', 1, 0), IF(m.role = 'owner', c.dateToOpen, NULL)
+FROM capsules c
+JOIN capsuleMembers m ON m.capsuleId = c.id
+WHERE c.emailSent = TRUE;
+
+ALTER TABLE capsules DROP COLUMN `emailSent`;
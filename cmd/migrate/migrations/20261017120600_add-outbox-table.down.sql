@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS outbox;
+
+UPDATE capsuleDeliveries SET status = 'pending' WHERE status = 'queued';
+
+ALTER TABLE capsuleDeliveries
+  MODIFY COLUMN `status` ENUM('pending', 'sent', 'failed') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,25 @@
+ALTER TABLE capsuleDeliveries
+  MODIFY COLUMN `status` ENUM('pending', 'queued', 'sent', 'failed') NOT NULL DEFAULT 'pending'; -- queued once handed to the outbox
+
+CREATE TABLE IF NOT EXISTS outbox (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `kind` VARCHAR(32) NOT NULL, -- how the payload is sent, eg. mail
+  `payload` JSON NOT NULL,
+  `deliveryId` INT UNSIGNED, -- capsule delivery this job fulfils, NULL for one off notifications
+
+  `status` ENUM('pending', 'processing', 'sent', 'dead') NOT NULL DEFAULT 'pending',
+  `attempts` INT UNSIGNED NOT NULL DEFAULT 0,
+  `maxAttempts` INT UNSIGNED NOT NULL,
+  `lastError` TEXT,
+  `runAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- job isn't picked up before this time, pushed back after each failure
+  `claimedBy` VARCHAR(64), -- worker currently processing the job
+  `claimedAt` TIMESTAMP NULL,
+  `sentAt` TIMESTAMP NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `deliveryId` (`deliveryId`),
+  KEY `statusRunAt` (`status`, `runAt`),
+  FOREIGN KEY (`deliveryId`) REFERENCES capsuleDeliveries(`id`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS devices (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` INT UNSIGNED NOT NULL,
+  `platform` ENUM('ios', 'android') NOT NULL,
+  `token` VARCHAR(255) NOT NULL, -- apns device token or fcm registration token
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token` (`token`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
@@ -0,0 +1,6 @@
+UPDATE capsuleDeliveries SET status = 'sent' WHERE status = 'skipped';
+
+ALTER TABLE capsuleDeliveries
+  MODIFY COLUMN `status` ENUM('pending', 'queued', 'sent', 'failed') NOT NULL DEFAULT 'pending';
+
+DROP TABLE IF EXISTS notificationPreferences;
@@ -0,0 +1,17 @@
+-- users get every notification unless they've turned it off, so only changed preferences are stored
+CREATE TABLE IF NOT EXISTS notificationPreferences (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` INT UNSIGNED NOT NULL,
+  `channel` ENUM('email', 'push', 'sms') NOT NULL,
+  `event` VARCHAR(32) NOT NULL, -- eg. capsule-ready
+
+  `enabled` BOOLEAN NOT NULL,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `userChannelEvent` (`userId`, `channel`, `event`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+ALTER TABLE capsuleDeliveries
+  MODIFY COLUMN `status` ENUM('pending', 'queued', 'sent', 'failed', 'skipped') NOT NULL DEFAULT 'pending'; -- skipped when the recipient turned the mail off
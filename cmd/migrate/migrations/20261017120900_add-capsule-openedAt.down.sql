@@ -0,0 +1,2 @@
+ALTER TABLE capsules
+  DROP COLUMN `openedAt`;
@@ -0,0 +1,5 @@
+ALTER TABLE capsules
+  ADD COLUMN `openedAt` TIMESTAMP NULL; -- when the capsule was actually opened, anniversaries count from here
+
+-- older capsules didn't record it, the day they were due is the best guess
+UPDATE capsules SET openedAt = dateToOpen WHERE sealed = 'opened';
@@ -0,0 +1,2 @@
+ALTER TABLE capsuleMembers
+  DROP COLUMN `lastNudgedAt`;
@@ -0,0 +1,2 @@
+ALTER TABLE capsuleMembers
+  ADD COLUMN `lastNudgedAt` TIMESTAMP NULL; -- when the member was last reminded to seal, NULL if never
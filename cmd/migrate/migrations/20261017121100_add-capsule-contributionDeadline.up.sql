@@ -0,0 +1,2 @@
+ALTER TABLE capsules
+  ADD COLUMN `contributionDeadline` TIMESTAMP NULL; -- the capsule is sealed for everyone once this passes
@@ -0,0 +1,5 @@
+ALTER TABLE capsules
+  DROP COLUMN `timezone`;
+
+ALTER TABLE users
+  DROP COLUMN `timezone`;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+  ADD COLUMN `timezone` VARCHAR(64) NOT NULL DEFAULT 'UTC'; -- IANA timezone, eg. America/Vancouver
+
+ALTER TABLE capsules
+  ADD COLUMN `timezone` VARCHAR(64) NOT NULL DEFAULT 'UTC'; -- dates to open without a time are midnight here
@@ -0,0 +1,6 @@
+ALTER TABLE capsuleMembers
+  DROP COLUMN `openVotedAt`;
+
+ALTER TABLE capsules
+  DROP COLUMN `openPolicy`,
+  DROP COLUMN `openQuorum`;
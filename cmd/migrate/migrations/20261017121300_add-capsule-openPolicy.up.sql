@@ -0,0 +1,6 @@
+ALTER TABLE capsules
+  ADD COLUMN `openPolicy` ENUM('owner', 'any-member', 'quorum') NOT NULL DEFAULT 'owner', -- who can open the capsule once it's due
+  ADD COLUMN `openQuorum` INT UNSIGNED NOT NULL DEFAULT 0; -- votes needed under the quorum policy, 0 for a majority of members
+
+ALTER TABLE capsuleMembers
+  ADD COLUMN `openVotedAt` TIMESTAMP NULL; -- when the member voted to open the capsule
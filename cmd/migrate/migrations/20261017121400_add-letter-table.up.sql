@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS letters (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` INT UNSIGNED NOT NULL,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `recipientId` INT UNSIGNED NOT NULL, -- the only member who can read the letter
+  `unlockAt` TIMESTAMP NOT NULL, -- readable once this passes and the capsule is open
+
+  `letter` VARCHAR(5000) NOT NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  KEY `capsuleRecipient` (`capsuleId`, `recipientId`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`recipientId`) REFERENCES users(`id`)
+);
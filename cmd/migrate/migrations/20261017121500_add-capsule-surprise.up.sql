@@ -0,0 +1,2 @@
+ALTER TABLE capsules
+  ADD COLUMN `surprise` BOOLEAN NOT NULL DEFAULT FALSE; -- members only see their own contributions until the capsule is opened
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS giftRecipients (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `capsuleId` INT UNSIGNED NOT NULL,
+  `senderId` INT UNSIGNED NOT NULL, -- the member who added the recipient
+  `name` VARCHAR(255) NOT NULL,
+  `email` VARCHAR(255) NOT NULL DEFAULT '',
+  `phone` VARCHAR(10) NOT NULL DEFAULT '',
+  `userId` INT UNSIGNED, -- set once the recipient has an account, or if they already had one
+
+  `deliveredAt` TIMESTAMP NULL, -- when the link was sent after the capsule opened
+  `claimedAt` TIMESTAMP NULL,
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`capsuleId`) REFERENCES capsules(`id`),
+  FOREIGN KEY (`senderId`) REFERENCES users(`id`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
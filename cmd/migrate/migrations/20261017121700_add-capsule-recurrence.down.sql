@@ -0,0 +1,7 @@
+ALTER TABLE capsules
+  DROP COLUMN `recurrenceUnit`,
+  DROP COLUMN `recurrenceInterval`,
+  DROP COLUMN `namePattern`,
+  DROP COLUMN `seriesId`,
+  DROP COLUMN `edition`,
+  DROP COLUMN `renewedAt`;
@@ -0,0 +1,7 @@
+ALTER TABLE capsules
+  ADD COLUMN `recurrenceUnit` ENUM('day', 'week', 'month', 'year'), -- NULL for capsules that don't recur
+  ADD COLUMN `recurrenceInterval` INT UNSIGNED NOT NULL DEFAULT 1,
+  ADD COLUMN `namePattern` VARCHAR(255) NOT NULL DEFAULT '', -- name of the next edition, {year} and {edition} are filled in
+  ADD COLUMN `seriesId` INT UNSIGNED, -- id of the first edition, shared by every edition of a recurring capsule
+  ADD COLUMN `edition` INT UNSIGNED NOT NULL DEFAULT 1,
+  ADD COLUMN `renewedAt` TIMESTAMP NULL; -- when the next edition was created
@@ -0,0 +1,6 @@
+ALTER TABLE capsules
+  DROP FOREIGN KEY `capsules_templateId_fk`,
+  DROP COLUMN `templateId`;
+
+DROP TABLE IF EXISTS capsuleTemplatePrompts;
+DROP TABLE IF EXISTS capsuleTemplates;
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS capsuleTemplates (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` INT UNSIGNED, -- NULL for the templates we provide
+
+  `name` VARCHAR(255) NOT NULL,
+  `vessel` VARCHAR(32) NOT NULL,
+  `capsuleName` VARCHAR(255) NOT NULL, -- default name of capsules created from the template
+  `openAfterDays` INT UNSIGNED, -- capsules open this many days after they're created, NULL to pick when sealing
+
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS capsuleTemplatePrompts (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `templateId` INT UNSIGNED NOT NULL,
+  `prompt` VARCHAR(255) NOT NULL,
+  `position` INT UNSIGNED NOT NULL,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`templateId`) REFERENCES capsuleTemplates(`id`)
+);
+
+ALTER TABLE capsules
+  ADD COLUMN `templateId` INT UNSIGNED, -- members who join later get the template's prompts too
+  ADD CONSTRAINT `capsules_templateId_fk` FOREIGN KEY (`templateId`) REFERENCES capsuleTemplates(`id`);
+
+INSERT INTO capsuleTemplates (id, name, vessel, capsuleName, openAfterDays) VALUES
+  (1, 'New Year', 'bottle', 'New Year', 365),
+  (2, 'Graduation', 'suitcase', 'Class Of', 1825),
+  (3, 'Birthday', 'box', 'Birthday', 365),
+  (4, 'Wedding', 'box', 'Our Wedding', 3650);
+
+INSERT INTO capsuleTemplatePrompts (templateId, prompt, position) VALUES
+  (1, 'What was the highlight of your year?', 1),
+  (1, 'What are you hoping for next year?', 2),
+  (1, 'What do you want to remember about right now?', 3),
+  (2, 'Where do you see yourself in five years?', 1),
+  (2, 'What will you miss the most?', 2),
+  (2, 'What advice would you give your future self?', 3),
+  (3, 'What made this year special?', 1),
+  (3, 'What do you wish for the year ahead?', 2),
+  (4, 'What was your favourite moment of the day?', 1),
+  (4, 'What do you wish for the couple?', 2),
+  (4, 'What advice would you give them?', 3);
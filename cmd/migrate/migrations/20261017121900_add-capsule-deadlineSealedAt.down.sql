@@ -0,0 +1,4 @@
+UPDATE capsules SET contributionDeadline = deadlineSealedAt WHERE deadlineSealedAt IS NOT NULL;
+
+ALTER TABLE capsules
+  DROP COLUMN `deadlineSealedAt`;
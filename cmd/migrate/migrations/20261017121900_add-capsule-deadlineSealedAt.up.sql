@@ -0,0 +1,5 @@
+ALTER TABLE capsules
+  ADD COLUMN `deadlineSealedAt` TIMESTAMP NULL; -- when the capsule was sealed by its contribution deadline, which is cleared once sealed
+
+-- capsules already sealed by their deadline still have it set
+UPDATE capsules SET deadlineSealedAt = contributionDeadline, contributionDeadline = NULL WHERE sealed != 'preseal' AND contributionDeadline IS NOT NULL;
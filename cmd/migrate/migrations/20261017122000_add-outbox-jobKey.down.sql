@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+  DROP INDEX `jobKey`,
+  DROP COLUMN `jobKey`;
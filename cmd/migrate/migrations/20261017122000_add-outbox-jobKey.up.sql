@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+  ADD COLUMN `jobKey` VARCHAR(64), -- a job with a key is only ever queued once, so retrying whatever queued it doesn't send it twice
+  ADD UNIQUE KEY `jobKey` (`jobKey`);
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -18,9 +19,43 @@ type Config struct {
 	DBName                 string
 	JWTExpirationInSeconds int64
 	JWTSecret              string
+	LinkSecret             string
 	GCSBucketName          string
-	GmailAppPassword       string
 	AdminAPIKey            string
+
+	DefaultCapsuleMemberLimit int64
+	MaxCapsuleMemberLimit     int64
+	InviteExpirationInSeconds int64
+
+	PasswordResetExpirationInSeconds int64
+
+	SchedulerIntervalInSeconds int64
+	SchedulerAutoOpen          bool
+	ClockBackend               string
+	CountdownReminderDays      []int64
+	NudgeCooldownInSeconds     int64
+	AutoNudgeAfterDays         int64
+
+	MailBackend  string
+	MailFrom     string
+	MailDropDir  string
+	MailAttempts int64
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	OutboxWorkers               int64
+	OutboxPollIntervalInSeconds int64
+	OutboxBackoffInSeconds      int64
+
+	PushBackend        string
+	APNSKeyPath        string
+	APNSKeyID          string
+	APNSTeamID         string
+	APNSTopic          string
+	APNSProduction     bool
+	FCMCredentialsPath string
 }
 
 // create global variable so that env isn't reinitialized every time it's called
@@ -39,9 +74,43 @@ func initConfig() Config {
 		DBName:                 getEnv("DB_NAME", "retrospect"),
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXP", 3600*24*7),
 		JWTSecret:              getEnv("JWT_SECRET", "sneakysneaky"),
+		LinkSecret:             getEnv("LINK_SECRET", "sneakylinks"),
 		GCSBucketName:          getEnv("BUCKET_NAME", "retrospect_file_bucket"),
-		GmailAppPassword:       getEnv("GMAIL_APP_PASSWORD", ""),
 		AdminAPIKey:            getEnv("ADMIN_API_KEY", "spartan"),
+
+		DefaultCapsuleMemberLimit: getEnvAsInt("DEFAULT_CAPSULE_MEMBER_LIMIT", 10),
+		MaxCapsuleMemberLimit:     getEnvAsInt("MAX_CAPSULE_MEMBER_LIMIT", 50),
+		InviteExpirationInSeconds: getEnvAsInt("INVITE_EXP", 3600*24*14),
+
+		PasswordResetExpirationInSeconds: getEnvAsInt("PASSWORD_RESET_EXP", 3600),
+
+		SchedulerIntervalInSeconds: getEnvAsInt("SCHEDULER_INTERVAL", 300),
+		SchedulerAutoOpen:          getEnvAsBool("SCHEDULER_AUTO_OPEN", false),
+		ClockBackend:               getEnv("CLOCK", "real"),                                       // real or fake, fake can be moved forward to test capsule lifecycles
+		CountdownReminderDays:      getEnvAsIntList("COUNTDOWN_REMINDER_DAYS", []int64{30, 7, 1}), // days before opening to remind members
+		NudgeCooldownInSeconds:     getEnvAsInt("NUDGE_COOLDOWN", 3600*24),                        // least time between nudges to the same member
+		AutoNudgeAfterDays:         getEnvAsInt("AUTO_NUDGE_AFTER_DAYS", 0),                       // nudge unsealed members of capsules this old, 0 to turn off
+
+		MailBackend:  getEnv("MAIL_BACKEND", "smtp"), // smtp, file or memory
+		MailFrom:     getEnv("MAIL_FROM", "retrospect.space@gmail.com"),
+		MailDropDir:  getEnv("MAIL_DROP_DIR", "tmp/mail"),
+		MailAttempts: getEnvAsInt("MAIL_ATTEMPTS", 5), // times a mail is tried before it's dead lettered
+		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", "retrospect.space@gmail.com"),
+		SMTPPassword: getEnv("SMTP_PASSWORD", getEnv("GMAIL_APP_PASSWORD", "")),
+
+		OutboxWorkers:               getEnvAsInt("OUTBOX_WORKERS", 2),
+		OutboxPollIntervalInSeconds: getEnvAsInt("OUTBOX_POLL_INTERVAL", 5),
+		OutboxBackoffInSeconds:      getEnvAsInt("OUTBOX_BACKOFF", 30), // wait before the first retry, doubled after each failure
+
+		PushBackend:        getEnv("PUSH_BACKEND", "live"), // live or fake
+		APNSKeyPath:        getEnv("APNS_KEY_PATH", ""),    // .p8 key, ios pushes are disabled without it
+		APNSKeyID:          getEnv("APNS_KEY_ID", ""),
+		APNSTeamID:         getEnv("APNS_TEAM_ID", ""),
+		APNSTopic:          getEnv("APNS_TOPIC", "space.retrospect.app"),
+		APNSProduction:     getEnvAsBool("APNS_PRODUCTION", false),
+		FCMCredentialsPath: getEnv("FCM_CREDENTIALS_PATH", ""), // service account json, android pushes are disabled without it
 	}
 }
 
@@ -62,3 +131,33 @@ func getEnvAsInt(key string, fallback int64) int64 {
 	}
 	return fallback
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fallback
+		}
+		return boolValue
+	}
+	return fallback
+}
+
+func getEnvAsIntList(key string, fallback []int64) []int64 {
+	if value, ok := os.LookupEnv(key); ok {
+		var integerValues []int64
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			integerValue, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return fallback
+			}
+			integerValues = append(integerValues, integerValue)
+		}
+		return integerValues
+	}
+	return fallback
+}
@@ -35,8 +35,11 @@ func scanRowIntoAudio(row *sql.Rows) (*types.Audio, error) {
 	return audio, nil
 }
 
-func (audioStore *AudioStore) GetAudios(capsuleID uint) ([]types.Audio, error) {
-	rows, err := audioStore.db.Query("SELECT * FROM audios WHERE capsuleId = ?", capsuleID)
+func (audioStore *AudioStore) GetAudios(capsuleID uint, userID uint) ([]types.Audio, error) {
+	rows, err := audioStore.db.Query(
+		"SELECT a.* FROM audios a JOIN capsules c ON a.capsuleId = c.id WHERE a.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR a.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +100,17 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, userStore types.UserStore) http.H
 	}
 }
 
+// WithAdminAPIKey only lets through requests carrying the admin api key, for routes used by operators rather than users
+func WithAdminAPIKey(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if config.Envs.AdminAPIKey != r.Header.Get("AdminAPIKey") {
+			utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid admin api key"))
+			return
+		}
+		handlerFunc(w, r)
+	}
+}
+
 func GetUserIdFromContext(ctx context.Context) uint {
 	userID, ok := ctx.Value(UserKey).(uint)
 	if !ok {
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+)
+
+// SignLink creates a signature for a value embedded in a public link, so the link can't be forged or tampered with
+func SignLink(value string) string {
+	mac := hmac.New(sha256.New, []byte(config.Envs.LinkSecret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyLink(value string, signature string) bool {
+	return hmac.Equal([]byte(SignLink(value)), []byte(signature))
+}
+
+// CreateUnsubscribeToken lets whoever has the email turn off that kind of email without logging in
+func CreateUnsubscribeToken(userId uint, event string) string {
+	claims := fmt.Sprintf("%d.%s", userId, event)
+	return claims + "." + SignLink("unsubscribe."+claims)
+}
+
+func VerifyUnsubscribeToken(token string) (uint, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", fmt.Errorf("invalid unsubscribe token")
+	}
+	if !VerifyLink("unsubscribe."+parts[0]+"."+parts[1], parts[2]) {
+		return 0, "", fmt.Errorf("invalid unsubscribe token")
+	}
+	userId, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid unsubscribe token")
+	}
+
+	return uint(userId), parts[1], nil
+}
+
+// CreateGiftToken gives a gift recipient read only access to the capsule once it's opened, without an account
+func CreateGiftToken(recipientId uint) string {
+	claims := fmt.Sprintf("%d", recipientId)
+	return claims + "." + SignLink("gift."+claims)
+}
+
+func VerifyGiftToken(token string) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid gift token")
+	}
+	if !VerifyLink("gift."+parts[0], parts[1]) {
+		return 0, fmt.Errorf("invalid gift token")
+	}
+	recipientId, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gift token")
+	}
+
+	return uint(recipientId), nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+)
+
+func TestVerifyGiftToken(t *testing.T) {
+	token := CreateGiftToken(42)
+	recipientId, err := VerifyGiftToken(token)
+	if err != nil || recipientId != 42 {
+		t.Fatalf("VerifyGiftToken(%q) = %d, %v", token, recipientId, err)
+	}
+
+	claims, signature, _ := strings.Cut(token, ".")
+	tampered := map[string]string{
+		"other recipient":     "43." + signature,
+		"changed signature":   claims + "." + flipLastChar(signature),
+		"missing signature":   claims,
+		"extra part":          token + ".1",
+		"unsubscribe token":   CreateUnsubscribeToken(42, "gift-ready"),
+		"signed for a share":  claims + "." + SignLink(claims),
+		"non numeric id":      "abc." + SignLink("gift.abc"),
+		"empty":               "",
+		"signature as claims": signature + "." + claims,
+	}
+	for name, token := range tampered {
+		if recipientId, err := VerifyGiftToken(token); err == nil {
+			t.Errorf("%s: VerifyGiftToken(%q) accepted it for recipient %d", name, token, recipientId)
+		}
+	}
+}
+
+func TestVerifyUnsubscribeToken(t *testing.T) {
+	token := CreateUnsubscribeToken(7, "capsule-ready")
+	userId, event, err := VerifyUnsubscribeToken(token)
+	if err != nil || userId != 7 || event != "capsule-ready" {
+		t.Fatalf("VerifyUnsubscribeToken(%q) = %d, %q, %v", token, userId, event, err)
+	}
+
+	parts := strings.Split(token, ".")
+	tampered := map[string]string{
+		"other user":        "8." + parts[1] + "." + parts[2],
+		"other event":       parts[0] + ".anniversary." + parts[2],
+		"changed signature": parts[0] + "." + parts[1] + "." + flipLastChar(parts[2]),
+		"missing event":     parts[0] + "." + parts[2],
+		"gift token":        CreateGiftToken(7),
+		"signed for a gift": parts[0] + "." + parts[1] + "." + SignLink("gift."+parts[0]+"."+parts[1]),
+		"empty":             "",
+	}
+	for name, token := range tampered {
+		if userId, event, err := VerifyUnsubscribeToken(token); err == nil {
+			t.Errorf("%s: VerifyUnsubscribeToken(%q) accepted it for user %d and %s", name, token, userId, event)
+		}
+	}
+}
+
+func TestTokensDependOnLinkSecret(t *testing.T) {
+	giftToken := CreateGiftToken(42)
+	unsubscribeToken := CreateUnsubscribeToken(7, "capsule-ready")
+
+	secret := config.Envs.LinkSecret
+	config.Envs.LinkSecret = secret + "-rotated"
+	defer func() { config.Envs.LinkSecret = secret }()
+
+	if _, err := VerifyGiftToken(giftToken); err == nil {
+		t.Error("gift token signed with another secret was accepted")
+	}
+	if _, _, err := VerifyUnsubscribeToken(unsubscribeToken); err == nil {
+		t.Error("unsubscribe token signed with another secret was accepted")
+	}
+}
+
+func flipLastChar(s string) string {
+	if strings.HasSuffix(s, "A") {
+		return s[:len(s)-1] + "B"
+	}
+	return s[:len(s)-1] + "A"
+}
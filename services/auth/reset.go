@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// CreatePasswordResetToken signs the user's current password hash too, so the token stops working once it's used
+func CreatePasswordResetToken(user *types.User) string {
+	expiresAt := time.Now().Add(time.Second * time.Duration(config.Envs.PasswordResetExpirationInSeconds)).Unix()
+	claims := fmt.Sprintf("%d.%d", user.ID, expiresAt)
+	return claims + "." + SignLink(claims+"."+user.Password)
+}
+
+func VerifyPasswordResetToken(token string, userStore types.UserStore) (*types.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid reset token")
+	}
+	userId, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reset token")
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reset token")
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, fmt.Errorf("reset token has expired")
+	}
+
+	user, err := userStore.GetUserById(uint(userId))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reset token")
+	}
+	if !VerifyLink(parts[0]+"."+parts[1]+"."+user.Password, parts[2]) {
+		return nil, fmt.Errorf("invalid reset token")
+	}
+
+	return user, nil
+}
@@ -0,0 +1,60 @@
+package capsule
+
+import (
+	"time"
+)
+
+func (capsuleStore *CapsuleStore) SetContributionDeadline(capsuleId uint, deadline *time.Time, dateToOpen *time.Time, timezone string) error {
+	// the deadline is left alone once it has passed, the scheduler is about to seal the capsule
+	_, err := capsuleStore.db.Exec(
+		"UPDATE capsules SET contributionDeadline = ?, dateToOpen = COALESCE(?, dateToOpen), timezone = ? WHERE id = ? AND sealed = 'preseal' AND (contributionDeadline IS NULL OR contributionDeadline > ?)",
+		deadline, dateToOpen, timezone, capsuleId, capsuleStore.clock.Now(),
+	)
+	return err
+}
+
+// SealExpiredCapsules seals every capsule whose contribution deadline has passed, along with all of its members.
+// everyone is told by the capsule-sealed reminder
+func (capsuleStore *CapsuleStore) SealExpiredCapsules() (int64, error) {
+	tx, err := capsuleStore.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := capsuleStore.clock.Now()
+	rows, err := tx.Query("SELECT id FROM capsules WHERE sealed = 'preseal' AND contributionDeadline <= ? FOR UPDATE", now)
+	if err != nil {
+		return 0, err
+	}
+	capsuleIds := make([]uint, 0)
+	for rows.Next() {
+		var capsuleId uint
+		if err := rows.Scan(&capsuleId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		capsuleIds = append(capsuleIds, capsuleId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, capsuleId := range capsuleIds {
+		// the deadline has done its job, deadlineSealedAt keeps when it sealed the capsule for the reminder
+		_, err = tx.Exec("UPDATE capsules SET sealed = 'sealed', deadlineSealedAt = contributionDeadline, contributionDeadline = NULL WHERE id = ?", capsuleId)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec("UPDATE capsuleMembers SET sealedAt = ? WHERE capsuleId = ? AND sealedAt IS NULL", now, capsuleId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(capsuleIds)), nil
+}
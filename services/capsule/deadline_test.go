@@ -0,0 +1,112 @@
+package capsule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/clock"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// deadlineCapsuleStore only implements what setting a deadline needs, anything else panics
+type deadlineCapsuleStore struct {
+	types.CapsuleStore
+	capsule  types.Capsule
+	deadline *time.Time
+	set      bool
+}
+
+func (store *deadlineCapsuleStore) AuthorizeCapsule(userId uint, capsuleId uint, permission string) (types.Capsule, error) {
+	return store.capsule, nil
+}
+
+func (store *deadlineCapsuleStore) SetContributionDeadline(capsuleId uint, deadline *time.Time, dateToOpen *time.Time, timezone string) error {
+	store.deadline = deadline
+	store.set = true
+	return nil
+}
+
+func setDeadline(t *testing.T, handler *Handler, deadline time.Time, dateToOpen time.Time) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(types.SetContributionDeadlinePayload{
+		CapsuleID:            1,
+		ContributionDeadline: &deadline,
+		DateToOpen:           dateToOpen.Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/capsules/deadline", bytes.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserKey, uint(1)))
+	w := httptest.NewRecorder()
+	handler.handleSetContributionDeadline(w, r)
+	return w
+}
+
+func TestSetContributionDeadlineFollowsClock(t *testing.T) {
+	fakeClock := &clock.FakeClock{}
+	store := &deadlineCapsuleStore{capsule: types.Capsule{ID: 1, Sealed: "preseal", Role: types.CapsuleRoleOwner}}
+	handler := &Handler{capsuleStore: store, clock: fakeClock}
+
+	deadline := fakeClock.Now().Add(24 * time.Hour)
+	dateToOpen := deadline.AddDate(1, 0, 0)
+	if w := setDeadline(t, handler, deadline, dateToOpen); w.Code != http.StatusOK {
+		t.Fatalf("a deadline tomorrow was refused with %d: %s", w.Code, w.Body)
+	}
+	if !store.set || !store.deadline.Equal(deadline) {
+		t.Errorf("the deadline wasn't saved, got %v", store.deadline)
+	}
+
+	// the same deadline is in the past once the clock moves beyond it
+	store.set = false
+	fakeClock.Advance(25 * time.Hour)
+	if w := setDeadline(t, handler, deadline, dateToOpen); w.Code != http.StatusBadRequest {
+		t.Errorf("a deadline in the past got %d", w.Code)
+	}
+	if store.set {
+		t.Error("a deadline in the past was saved")
+	}
+}
+
+func TestSetContributionDeadlineAfterItPassed(t *testing.T) {
+	fakeClock := &clock.FakeClock{}
+	current := fakeClock.Now().Add(time.Hour)
+	store := &deadlineCapsuleStore{capsule: types.Capsule{ID: 1, Sealed: "preseal", Role: types.CapsuleRoleOwner, ContributionDeadline: &current}}
+	handler := &Handler{capsuleStore: store, clock: fakeClock}
+
+	later := fakeClock.Now().Add(48 * time.Hour)
+	if w := setDeadline(t, handler, later, later.AddDate(0, 1, 0)); w.Code != http.StatusOK {
+		t.Fatalf("moving a deadline that hasn't passed was refused with %d: %s", w.Code, w.Body)
+	}
+
+	// once the current deadline passes the scheduler is about to seal the capsule, so it can't be moved
+	store.set = false
+	fakeClock.Advance(2 * time.Hour)
+	if w := setDeadline(t, handler, later, later.AddDate(0, 1, 0)); w.Code != http.StatusBadRequest {
+		t.Errorf("moving a deadline that passed got %d", w.Code)
+	}
+	if store.set {
+		t.Error("a deadline that passed was moved")
+	}
+}
+
+func TestSetContributionDeadlineBeforeDateToOpen(t *testing.T) {
+	fakeClock := &clock.FakeClock{}
+	store := &deadlineCapsuleStore{capsule: types.Capsule{ID: 1, Sealed: "preseal", Role: types.CapsuleRoleOwner}}
+	handler := &Handler{capsuleStore: store, clock: fakeClock}
+
+	deadline := fakeClock.Now().Add(48 * time.Hour)
+	if w := setDeadline(t, handler, deadline, deadline.Add(-time.Hour)); w.Code != http.StatusBadRequest {
+		t.Errorf("a capsule opening before its deadline got %d", w.Code)
+	}
+	if store.set {
+		t.Error("a capsule opening before its deadline was saved")
+	}
+}
@@ -0,0 +1,193 @@
+package capsule
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+func nudgeCooldown() time.Duration {
+	return time.Second * time.Duration(config.Envs.NudgeCooldownInSeconds)
+}
+
+// nextNudgeAt is when a member nudged at lastNudgedAt can be nudged again, nil if they can be now
+func nextNudgeAt(lastNudgedAt *time.Time, now time.Time) *time.Time {
+	if lastNudgedAt == nil {
+		return nil
+	}
+	next := lastNudgedAt.Add(nudgeCooldown())
+	if !next.After(now) {
+		return nil
+	}
+	return &next
+}
+
+// GetOutstandingCapsuleMembers lists the members who still have to seal before the owner can, viewers don't count
+func (capsuleStore *CapsuleStore) GetOutstandingCapsuleMembers(capsuleId uint) ([]types.OutstandingCapsuleMember, error) {
+	getOutstandingMembersQuery := `
+		SELECT m.userId, u.name, u.email, m.role, m.lastNudgedAt
+		FROM capsuleMembers m
+		JOIN users u ON m.userId = u.id
+		WHERE m.capsuleId = ? AND m.role NOT IN ('owner', 'viewer') AND m.sealedAt IS NULL
+		ORDER BY m.joinedAt
+	`
+	rows, err := capsuleStore.db.Query(getOutstandingMembersQuery, capsuleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]types.OutstandingCapsuleMember, 0)
+	for rows.Next() {
+		var member types.OutstandingCapsuleMember
+		if err := rows.Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.LastNudgedAt); err != nil {
+			return nil, err
+		}
+		member.NextNudgeAt = nextNudgeAt(member.LastNudgedAt, capsuleStore.clock.Now())
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// NudgeCapsuleMembers reminds outstanding members to seal, or only userId if it isn't 0.
+// members nudged within the cooldown are left alone, every outstanding member is returned either way
+func (capsuleStore *CapsuleStore) NudgeCapsuleMembers(capsuleId uint, userId uint) ([]types.OutstandingCapsuleMember, error) {
+	return capsuleStore.nudgeCapsuleMembers(capsuleId, func(member types.OutstandingCapsuleMember) bool {
+		return userId == 0 || member.UserID == userId
+	})
+}
+
+// NudgeStaleCapsules nudges members who were never nudged in capsules that have been in preseal for too long
+func (capsuleStore *CapsuleStore) NudgeStaleCapsules() (int64, error) {
+	findStaleCapsulesQuery := `
+		SELECT DISTINCT c.id
+		FROM capsules c
+		JOIN capsuleMembers m ON m.capsuleId = c.id
+		WHERE c.sealed = 'preseal' AND c.createdAt <= ?
+			AND m.role NOT IN ('owner', 'viewer') AND m.sealedAt IS NULL AND m.lastNudgedAt IS NULL
+		ORDER BY c.id
+	`
+	staleBefore := capsuleStore.clock.Now().AddDate(0, 0, -int(config.Envs.AutoNudgeAfterDays))
+	rows, err := capsuleStore.db.Query(findStaleCapsulesQuery, staleBefore)
+	if err != nil {
+		return 0, err
+	}
+	capsuleIds := make([]uint, 0)
+	for rows.Next() {
+		var capsuleId uint
+		if err := rows.Scan(&capsuleId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		capsuleIds = append(capsuleIds, capsuleId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var nudged int64
+	for _, capsuleId := range capsuleIds {
+		members, err := capsuleStore.nudgeCapsuleMembers(capsuleId, func(member types.OutstandingCapsuleMember) bool {
+			return member.LastNudgedAt == nil
+		})
+		if err != nil {
+			return nudged, fmt.Errorf("error nudging members of capsule %d: %w", capsuleId, err)
+		}
+		for _, member := range members {
+			if member.Nudged {
+				nudged++
+			}
+		}
+	}
+	return nudged, nil
+}
+
+func (capsuleStore *CapsuleStore) nudgeCapsuleMembers(capsuleId uint, shouldNudge func(member types.OutstandingCapsuleMember) bool) ([]types.OutstandingCapsuleMember, error) {
+	var capsuleName, vessel, timezone, ownerName string
+	var dateToOpen *time.Time
+	err := capsuleStore.db.QueryRow(
+		"SELECT c.name, c.vessel, c.dateToOpen, c.timezone, u.name FROM capsules c JOIN users u ON c.capsuleOwnerId = u.id WHERE c.id = ?",
+		capsuleId,
+	).Scan(&capsuleName, &vessel, &dateToOpen, &timezone, &ownerName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("capsule not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := capsuleStore.GetOutstandingCapsuleMembers(capsuleId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		member := &members[i]
+		if member.NextNudgeAt != nil || !shouldNudge(*member) {
+			continue
+		}
+
+		// claiming the nudge first keeps two requests at once from both nudging the member
+		now := capsuleStore.clock.Now()
+		res, err := capsuleStore.db.Exec(
+			"UPDATE capsuleMembers SET lastNudgedAt = ? WHERE capsuleId = ? AND userId = ? AND (lastNudgedAt IS NULL OR lastNudgedAt <= ?)",
+			now, capsuleId, member.UserID, now.Add(-nudgeCooldown()),
+		)
+		if err != nil {
+			return nil, err
+		}
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		member.LastNudgedAt = &now
+		member.NextNudgeAt = nextNudgeAt(&now, now)
+		member.Nudged = true
+
+		err = capsuleStore.outboxStore.EnqueuePush(member.UserID, push.SealNudge(capsuleId, capsuleName, ownerName), "")
+		if err != nil {
+			return nil, err
+		}
+
+		var mailEnabled bool
+		err = capsuleStore.db.QueryRow(
+			"SELECT COALESCE((SELECT enabled FROM notificationPreferences WHERE userId = ? AND channel = 'email' AND event = ?), TRUE)",
+			member.UserID, types.NotificationEventSealNudge,
+		).Scan(&mailEnabled)
+		if err != nil {
+			return nil, err
+		}
+		if !mailEnabled {
+			continue
+		}
+
+		data := mail.CapsuleTemplateData(capsuleId, capsuleName, vessel, dateToOpen, timezone)
+		data.RecipientName = member.Name
+		data.ActorName = ownerName
+		data.UnsubscribeLink = mail.UnsubscribeLink(member.UserID, types.NotificationEventSealNudge)
+		nudgeMail, err := mail.RenderMail([]string{member.Email}, mail.TemplateSealNudge, data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = capsuleStore.outboxStore.EnqueueMail(nudgeMail, nil, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return members, nil
+}
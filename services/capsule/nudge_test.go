@@ -0,0 +1,36 @@
+package capsule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/clock"
+)
+
+func TestNextNudgeAtCooldown(t *testing.T) {
+	cooldownInSeconds := config.Envs.NudgeCooldownInSeconds
+	config.Envs.NudgeCooldownInSeconds = 3600
+	defer func() { config.Envs.NudgeCooldownInSeconds = cooldownInSeconds }()
+
+	fakeClock := &clock.FakeClock{}
+	if next := nextNudgeAt(nil, fakeClock.Now()); next != nil {
+		t.Errorf("a member who was never nudged has to wait until %v", next)
+	}
+
+	nudgedAt := fakeClock.Now()
+	next := nextNudgeAt(&nudgedAt, fakeClock.Now())
+	if next == nil || !next.Equal(nudgedAt.Add(time.Hour)) {
+		t.Fatalf("right after a nudge the next one is at %v, want %v", next, nudgedAt.Add(time.Hour))
+	}
+
+	fakeClock.Advance(59 * time.Minute)
+	if next := nextNudgeAt(&nudgedAt, fakeClock.Now()); next == nil {
+		t.Error("the cooldown ended a minute early")
+	}
+
+	fakeClock.Advance(time.Minute)
+	if next := nextNudgeAt(&nudgedAt, fakeClock.Now()); next != nil {
+		t.Errorf("the cooldown is over but the member has to wait until %v", next)
+	}
+}
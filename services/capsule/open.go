@@ -0,0 +1,63 @@
+package capsule
+
+import (
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// votesNeeded is how many votes open a capsule under the quorum policy, never more than there are members
+func votesNeeded(members uint, openQuorum uint) uint {
+	needed := openQuorum
+	if needed == 0 {
+		needed = members/2 + 1
+	}
+	if needed > members {
+		needed = members
+	}
+	return needed
+}
+
+func (capsuleStore *CapsuleStore) SetOpenPolicy(capsuleId uint, openPolicy string, openQuorum uint) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET openPolicy = ?, openQuorum = ? WHERE id = ?", openPolicy, openQuorum, capsuleId)
+	return err
+}
+
+// VoteToOpenCapsule records the member's vote and opens the capsule once enough members have voted.
+// votes are counted against whoever is still in the capsule, so they're recounted on every vote
+func (capsuleStore *CapsuleStore) VoteToOpenCapsule(userId uint, capsuleId uint) (types.OpenCapsuleResponse, error) {
+	var response types.OpenCapsuleResponse
+
+	now := capsuleStore.clock.Now()
+	_, err := capsuleStore.db.Exec(
+		"UPDATE capsuleMembers SET openVotedAt = COALESCE(openVotedAt, ?) WHERE capsuleId = ? AND userId = ?",
+		now, capsuleId, userId,
+	)
+	if err != nil {
+		return response, err
+	}
+
+	var members, openQuorum uint
+	err = capsuleStore.db.QueryRow(
+		"SELECT COUNT(*), COUNT(m.openVotedAt), c.openQuorum FROM capsuleMembers m JOIN capsules c ON m.capsuleId = c.id WHERE c.id = ? GROUP BY c.openQuorum",
+		capsuleId,
+	).Scan(&members, &response.Votes, &openQuorum)
+	if err != nil {
+		return response, err
+	}
+
+	response.VotesNeeded = votesNeeded(members, openQuorum)
+	if response.Votes < response.VotesNeeded {
+		return response, nil
+	}
+
+	res, err := capsuleStore.db.Exec("UPDATE capsules SET sealed = 'opened', openedAt = COALESCE(openedAt, ?) WHERE id = ? AND sealed = 'sealed'", now, capsuleId)
+	if err != nil {
+		return response, err
+	}
+	// only the vote that actually opened it reports so, later votes find it already opened
+	opened, err := res.RowsAffected()
+	if err != nil {
+		return response, err
+	}
+	response.Opened = opened == 1
+	return response, nil
+}
@@ -0,0 +1,72 @@
+package capsule
+
+import (
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// permissions granted to each member role
+var rolePermissions = map[string][]string{
+	types.CapsuleRoleOwner: {
+		types.CapsulePermissionView,
+		types.CapsulePermissionContribute,
+		types.CapsulePermissionRename,
+		types.CapsulePermissionSeal,
+		types.CapsulePermissionOpen,
+		types.CapsulePermissionDelete,
+		types.CapsulePermissionManageMembers,
+		types.CapsulePermissionTransfer,
+		types.CapsulePermissionSetOpenPolicy,
+		types.CapsulePermissionSetSurprise,
+		types.CapsulePermissionSetRecurrence,
+	},
+	types.CapsuleRoleEditor: {
+		types.CapsulePermissionView,
+		types.CapsulePermissionContribute,
+		types.CapsulePermissionRename,
+		types.CapsulePermissionSeal,
+		types.CapsulePermissionOpen,
+		types.CapsulePermissionLeave,
+	},
+	types.CapsuleRoleContributor: {
+		types.CapsulePermissionView,
+		types.CapsulePermissionContribute,
+		types.CapsulePermissionLeave,
+	},
+	types.CapsuleRoleViewer: {
+		types.CapsulePermissionView,
+		types.CapsulePermissionLeave,
+	},
+}
+
+// permissions that can only be used while the capsule is still being filled
+var presealPermissions = map[string]bool{
+	types.CapsulePermissionContribute:    true,
+	types.CapsulePermissionRename:        true,
+	types.CapsulePermissionSeal:          true,
+	types.CapsulePermissionManageMembers: true,
+	types.CapsulePermissionTransfer:      true,
+	types.CapsulePermissionLeave:         true,
+	types.CapsulePermissionSetSurprise:   true,
+	types.CapsulePermissionSetOpenPolicy: true,
+}
+
+func hasPermission(role string, permission string) bool {
+	for _, rolePermission := range rolePermissions[role] {
+		if rolePermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPermission(capsule types.Capsule, permission string) error {
+	if !hasPermission(capsule.Role, permission) {
+		return fmt.Errorf("%w: you do not have permission to %s this capsule", types.ErrCapsulePermissionDenied, permission)
+	}
+	if presealPermissions[permission] && capsule.Sealed != "preseal" {
+		return fmt.Errorf("%w: capsule cannot be modified because it has already been sealed or opened", types.ErrCapsulePermissionDenied)
+	}
+	return nil
+}
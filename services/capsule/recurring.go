@@ -0,0 +1,236 @@
+package capsule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+)
+
+// addMonths is time.AddDate for months, except days past the end of the month are clamped to the last day
+// instead of spilling into the next month, so a capsule opening on Jan 31st next opens on Feb 28th
+func addMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// nextDateToOpen steps the date to open forward by the recurrence until it's in the future,
+// in the capsule's timezone so a capsule opening at local midnight keeps doing so
+func nextDateToOpen(dateToOpen time.Time, timezone string, recurrenceUnit string, recurrenceInterval uint, now time.Time) time.Time {
+	local := dateToOpen.In(utils.LoadLocation(timezone))
+	interval := int(recurrenceInterval)
+	if interval == 0 {
+		interval = 1
+	}
+
+	next := local
+	for step := interval; !next.After(now); step += interval {
+		switch recurrenceUnit {
+		case "day":
+			next = local.AddDate(0, 0, step)
+		case "week":
+			next = local.AddDate(0, 0, 7*step)
+		case "month":
+			next = addMonths(local, step)
+		default:
+			next = addMonths(local, 12*step)
+		}
+	}
+	return next.UTC()
+}
+
+// editionName fills in the name pattern for an edition, {year} is the year it opens in the capsule's timezone
+func editionName(name string, namePattern string, edition uint, dateToOpen time.Time, timezone string) string {
+	if namePattern == "" {
+		return name
+	}
+	year := dateToOpen.In(utils.LoadLocation(timezone)).Year()
+	return strings.NewReplacer(
+		"{year}", strconv.Itoa(year),
+		"{edition}", strconv.FormatUint(uint64(edition), 10),
+	).Replace(namePattern)
+}
+
+// SetCapsuleRecurrence starts the capsule's series, or stops it from recurring when the unit is nil
+func (capsuleStore *CapsuleStore) SetCapsuleRecurrence(capsuleId uint, recurrenceUnit *string, recurrenceInterval uint, namePattern string) error {
+	if recurrenceInterval == 0 {
+		recurrenceInterval = 1
+	}
+	_, err := capsuleStore.db.Exec(
+		"UPDATE capsules SET recurrenceUnit = ?, recurrenceInterval = ?, namePattern = ?, seriesId = COALESCE(seriesId, id) WHERE id = ?",
+		recurrenceUnit, recurrenceInterval, namePattern, capsuleId,
+	)
+	return err
+}
+
+// CreateNextEdition creates the next capsule in the series of an opened recurring capsule, with the same
+// members, settings and prompts. it returns 0 if the capsule doesn't recur or its next edition already exists
+func (capsuleStore *CapsuleStore) CreateNextEdition(capsuleId uint) (uint, error) {
+	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE id = ?", capsuleId)
+	if err != nil {
+		return 0, err
+	}
+	capsule := new(types.Capsule)
+	for rows.Next() {
+		capsule, err = scanRowIntoCapsule(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+	}
+	rows.Close()
+	if capsule.ID != capsuleId {
+		return 0, fmt.Errorf("capsule not found")
+	}
+	if capsule.RecurrenceUnit == nil || capsule.Sealed != "opened" || capsule.RenewedAt != nil || capsule.DateToOpen == nil {
+		return 0, nil
+	}
+
+	code, err := capsuleStore.generateUniqueCapsuleCode()
+	if err != nil {
+		return 0, err
+	}
+
+	now := capsuleStore.clock.Now()
+	dateToOpen := nextDateToOpen(*capsule.DateToOpen, capsule.Timezone, *capsule.RecurrenceUnit, capsule.RecurrenceInterval, now)
+	edition := capsule.Edition + 1
+	seriesId := capsule.ID
+	if capsule.SeriesID != nil {
+		seriesId = *capsule.SeriesID
+	}
+
+	tx, err := capsuleStore.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// claiming the renewal first keeps the scheduler and an open request from both creating the next edition
+	res, err := tx.Exec("UPDATE capsules SET renewedAt = ? WHERE id = ? AND renewedAt IS NULL", now, capsuleId)
+	if err != nil {
+		return 0, err
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if claimed == 0 {
+		return 0, nil
+	}
+
+	createEditionQuery := `
+		INSERT INTO capsules (
+			code, capsuleOwnerId, vessel, name, public, memberLimit, dateToOpen, timezone, openPolicy, openQuorum, surprise,
+			recurrenceUnit, recurrenceInterval, namePattern, seriesId, edition, templateId
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err = tx.Exec(
+		createEditionQuery,
+		code, capsule.CapsuleOwnerID, capsule.Vessel, editionName(capsule.Name, capsule.NamePattern, edition, dateToOpen, capsule.Timezone),
+		capsule.Public, capsule.MemberLimit, dateToOpen, capsule.Timezone, capsule.OpenPolicy, capsule.OpenQuorum, capsule.Surprise,
+		capsule.RecurrenceUnit, capsule.RecurrenceInterval, capsule.NamePattern, seriesId, edition, capsule.TemplateID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE capsules SET seriesId = ? WHERE id = ? AND seriesId IS NULL", seriesId, capsuleId)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("INSERT INTO capsuleMembers (capsuleId, userId, role) SELECT ?, userId, role FROM capsuleMembers WHERE capsuleId = ?", id, capsuleId)
+	if err != nil {
+		return 0, err
+	}
+
+	// every member who can contribute gets an empty answer to each of last edition's prompts
+	copyPromptsQuery := `
+		INSERT INTO questionAnswers (userId, capsuleId, prompt, answer)
+		SELECT m.userId, ?, p.prompt, ''
+		FROM (SELECT DISTINCT prompt FROM questionAnswers WHERE capsuleId = ?) p
+		CROSS JOIN capsuleMembers m
+		WHERE m.capsuleId = ? AND m.role != 'viewer'
+	`
+	_, err = tx.Exec(copyPromptsQuery, id, capsuleId, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), tx.Commit()
+}
+
+// RenewRecurringCapsules creates the next edition of every opened recurring capsule that doesn't have one yet
+func (capsuleStore *CapsuleStore) RenewRecurringCapsules() (int64, error) {
+	rows, err := capsuleStore.db.Query("SELECT id FROM capsules WHERE sealed = 'opened' AND recurrenceUnit IS NOT NULL AND renewedAt IS NULL ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	capsuleIds := make([]uint, 0)
+	for rows.Next() {
+		var capsuleId uint
+		if err := rows.Scan(&capsuleId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		capsuleIds = append(capsuleIds, capsuleId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var renewed int64
+	for _, capsuleId := range capsuleIds {
+		editionId, err := capsuleStore.CreateNextEdition(capsuleId)
+		if err != nil {
+			return renewed, fmt.Errorf("error creating the next edition of capsule %d: %w", capsuleId, err)
+		}
+		if editionId != 0 {
+			renewed++
+		}
+	}
+	return renewed, nil
+}
+
+// GetCapsuleSeries lists the editions of the capsule's series that the user is a member of, oldest first
+func (capsuleStore *CapsuleStore) GetCapsuleSeries(userId uint, capsuleId uint) ([]types.CapsuleEdition, error) {
+	getSeriesQuery := `
+		SELECT c.id, c.name, c.edition, c.dateToOpen, c.sealed
+		FROM capsules c
+		JOIN capsuleMembers m ON m.capsuleId = c.id AND m.userId = ?
+		WHERE c.id = ? OR c.seriesId = (SELECT seriesId FROM capsules WHERE id = ?)
+		ORDER BY c.edition, c.id
+	`
+	rows, err := capsuleStore.db.Query(getSeriesQuery, userId, capsuleId, capsuleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	editions := make([]types.CapsuleEdition, 0)
+	for rows.Next() {
+		var edition types.CapsuleEdition
+		if err := rows.Scan(&edition.ID, &edition.Name, &edition.Edition, &edition.DateToOpen, &edition.Sealed); err != nil {
+			return nil, err
+		}
+		editions = append(editions, edition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return editions, nil
+}
@@ -0,0 +1,218 @@
+package capsule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// capsuleReminder is a mail and push sent once to everyone in a capsule when dueCondition holds
+type capsuleReminder struct {
+	kind         string // delivery kind, each member only ever gets one delivery of a kind per capsule
+	event        string // checked against the member's notification preferences
+	template     string
+	dueCondition string // sql condition on the capsule c, the clock's time is clock.now
+	push         func(capsuleId uint, capsuleName string, daysLeft int) types.Push
+
+	// dueDeliveries replaces dueCondition for reminders about single items in a capsule rather than the whole capsule.
+	// it selects the capsuleId, userId and kind of every due delivery, where the kind is the reminder's kind and the item's id
+	dueDeliveries string
+}
+
+// capsuleReminders lists every reminder, the countdowns come from config
+func capsuleReminders() []capsuleReminder {
+	reminders := []capsuleReminder{
+		{
+			kind:         "capsule-ready",
+			event:        types.NotificationEventCapsuleReady,
+			template:     mail.TemplateCapsuleReady,
+			dueCondition: "c.sealed = 'sealed' AND c.dateToOpen < clock.now",
+			push: func(capsuleId uint, capsuleName string, daysLeft int) types.Push {
+				return push.CapsuleReady(capsuleId, capsuleName)
+			},
+		},
+		{
+			// only capsules sealed by their deadline, sealing by hand doesn't set deadlineSealedAt
+			kind:         "deadline-sealed",
+			event:        types.NotificationEventCapsuleSealed,
+			template:     mail.TemplateCapsuleSealed,
+			dueCondition: "c.sealed = 'sealed' AND c.deadlineSealedAt <= clock.now AND c.deadlineSealedAt > clock.now - INTERVAL 7 DAY",
+			push:         push.CapsuleSealed,
+		},
+		{
+			// the week long window catches up after downtime without mailing about capsules opened years ago
+			kind:         "anniversary",
+			event:        types.NotificationEventAnniversary,
+			template:     mail.TemplateCapsuleAnniversary,
+			dueCondition: "c.sealed = 'opened' AND c.openedAt <= clock.now - INTERVAL 1 YEAR AND c.openedAt > clock.now - INTERVAL 1 YEAR - INTERVAL 7 DAY",
+			push: func(capsuleId uint, capsuleName string, daysLeft int) types.Push {
+				return push.CapsuleAnniversary(capsuleId, capsuleName)
+			},
+		},
+		{
+			// letters that unlock with the capsule are covered by capsule-ready, only later ones get their own
+			kind:     "letter",
+			event:    types.NotificationEventLetterUnlock,
+			template: mail.TemplateLetterUnlocked,
+			dueDeliveries: `
+				SELECT l.capsuleId, l.recipientId, CONCAT('letter-', l.id)
+				FROM letters l
+				JOIN capsules c ON l.capsuleId = c.id
+				CROSS JOIN (SELECT CAST(? AS DATETIME) AS now) clock
+				WHERE c.sealed = 'opened' AND l.unlockAt > c.openedAt AND l.unlockAt <= clock.now
+			`,
+			push: func(capsuleId uint, capsuleName string, daysLeft int) types.Push {
+				return push.LetterUnlocked(capsuleId, capsuleName)
+			},
+		},
+	}
+
+	days := make([]int64, 0, len(config.Envs.CountdownReminderDays))
+	for _, day := range config.Envs.CountdownReminderDays {
+		if day > 0 {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] > days[j] })
+
+	// each countdown only covers the days until the next one, so a capsule sealed close to
+	// its date to open only gets the nearest countdown instead of all of them at once
+	for i, day := range days {
+		if i > 0 && days[i-1] == day {
+			continue
+		}
+		var nextDay int64
+		if i+1 < len(days) {
+			nextDay = days[i+1]
+		}
+
+		reminders = append(reminders, capsuleReminder{
+			kind:         fmt.Sprintf("countdown-%dd", day),
+			event:        types.NotificationEventCountdown,
+			template:     mail.TemplateCapsuleCountdown,
+			dueCondition: fmt.Sprintf("c.sealed = 'sealed' AND c.dateToOpen > clock.now + INTERVAL %d DAY AND c.dateToOpen <= clock.now + INTERVAL %d DAY", nextDay, day),
+			push:         push.CapsuleCountdown,
+		})
+	}
+
+	return reminders
+}
+
+// SendReminderMail queues every due reminder for every owner and member, one delivery per recipient
+func (capsuleStore *CapsuleStore) SendReminderMail() error {
+	for _, reminder := range capsuleReminders() {
+		err := capsuleStore.queueCapsuleReminder(reminder)
+		if err != nil {
+			return fmt.Errorf("error queueing %s reminders: %w", reminder.kind, err)
+		}
+	}
+	return nil
+}
+
+func (capsuleStore *CapsuleStore) queueCapsuleReminder(reminder capsuleReminder) error {
+	// queue a delivery for everyone in newly due capsules, existing deliveries are left alone
+	now := capsuleStore.clock.Now()
+	queueDeliveriesQuery := `
+		INSERT IGNORE INTO capsuleDeliveries (capsuleId, userId, kind)
+		SELECT c.id, m.userId, ?
+		FROM capsules c
+		JOIN capsuleMembers m ON m.capsuleId = c.id
+		CROSS JOIN (SELECT CAST(? AS DATETIME) AS now) clock
+		WHERE ` + reminder.dueCondition
+	queueDeliveriesArgs := []any{reminder.kind, now}
+	kindCondition, kind := "d.kind = ?", reminder.kind
+	if reminder.dueDeliveries != "" {
+		queueDeliveriesQuery = "INSERT IGNORE INTO capsuleDeliveries (capsuleId, userId, kind)" + reminder.dueDeliveries
+		queueDeliveriesArgs = []any{now}
+		kindCondition, kind = "d.kind LIKE ?", reminder.kind+"-%"
+	}
+	_, err := capsuleStore.db.Exec(queueDeliveriesQuery, queueDeliveriesArgs...)
+	if err != nil {
+		return err
+	}
+
+	findPendingDeliveriesQuery := `
+		SELECT d.id, c.id, c.name, c.vessel, c.dateToOpen, c.timezone, u.id, u.name, u.email, COALESCE(p.enabled, TRUE)
+		FROM capsuleDeliveries d
+		JOIN capsules c ON d.capsuleId = c.id
+		JOIN users u ON d.userId = u.id
+		LEFT JOIN notificationPreferences p ON p.userId = u.id AND p.channel = 'email' AND p.event = ?
+		WHERE ` + kindCondition + ` AND d.status = 'pending'
+		ORDER BY d.id
+		LIMIT 490
+	`
+	rows, err := capsuleStore.db.Query(findPendingDeliveriesQuery, reminder.event, kind)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// mail is nil when the recipient turned it off, the push has its own preference so it's still queued
+	type delivery struct {
+		id          uint
+		recipientId uint
+		mail        *types.Mail
+		push        types.Push
+	}
+	deliveries := make([]delivery, 0)
+	for rows.Next() {
+		var deliveryId, capsuleId, recipientId uint
+		var capsuleName, vessel, timezone, recipientName, email string
+		var dateToOpen *time.Time
+		var mailEnabled bool
+		if err := rows.Scan(&deliveryId, &capsuleId, &capsuleName, &vessel, &dateToOpen, &timezone, &recipientId, &recipientName, &email, &mailEnabled); err != nil {
+			return err
+		}
+
+		daysLeft := 0
+		if dateToOpen != nil {
+			daysLeft = int(math.Ceil(dateToOpen.Sub(now).Hours() / 24))
+		}
+
+		d := delivery{id: deliveryId, recipientId: recipientId, push: reminder.push(capsuleId, capsuleName, daysLeft)}
+		if mailEnabled {
+			data := mail.CapsuleTemplateData(capsuleId, capsuleName, vessel, dateToOpen, timezone)
+			data.RecipientName = recipientName
+			data.DaysLeft = daysLeft
+			data.UnsubscribeLink = mail.UnsubscribeLink(recipientId, reminder.event)
+			reminderMail, err := mail.RenderMail([]string{email}, reminder.template, data)
+			if err != nil {
+				return err
+			}
+			d.mail = &reminderMail
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// hand each recipient's mail and push to the outbox, which retries them on its own. both are keyed by the
+	// delivery so if queueing fails part way, the next run doesn't queue what already was again
+	for _, d := range deliveries {
+		status := "skipped"
+		if d.mail != nil {
+			_, err = capsuleStore.outboxStore.EnqueueMail(*d.mail, &d.id, "")
+			if err != nil {
+				return err
+			}
+			status = "queued"
+		}
+		err = capsuleStore.outboxStore.EnqueuePush(d.recipientId, d.push, fmt.Sprintf("delivery-%d", d.id))
+		if err != nil {
+			return err
+		}
+		_, err = capsuleStore.db.Exec("UPDATE capsuleDeliveries SET status = ? WHERE id = ?", status, d.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
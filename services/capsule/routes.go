@@ -1,26 +1,41 @@
 package capsule
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/TenacityLabs/retrospect-backend/config"
 	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/joinRequest"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
 	"github.com/TenacityLabs/retrospect-backend/types"
 	"github.com/TenacityLabs/retrospect-backend/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/skip2/go-qrcode"
 )
 
 type Handler struct {
 	capsuleStore        types.CapsuleStore
 	userStore           types.UserStore
 	fileStore           types.FileStore
+	joinRequestStore    types.JoinRequestStore
+	mailer              types.Mailer
+	pusher              types.Pusher
+	preferenceStore     types.NotificationPreferenceStore
+	clock               types.Clock
+	giftStore           types.GiftStore
+	templateStore       types.CapsuleTemplateStore
 	songStore           types.SongStore
 	questionAnswerStore types.QuestionAnswerStore
 	writingStore        types.WritingStore
+	letterStore         types.LetterStore
 	photoStore          types.PhotoStore
 	audioStore          types.AudioStore
 	doodleStore         types.DoodleStore
@@ -31,23 +46,39 @@ func NewHandler(
 	capsuleStore types.CapsuleStore,
 	userStore types.UserStore,
 	fileStore types.FileStore,
+	joinRequestStore types.JoinRequestStore,
+	mailer types.Mailer,
+	pusher types.Pusher,
+	preferenceStore types.NotificationPreferenceStore,
+	clock types.Clock,
+	giftStore types.GiftStore,
+	templateStore types.CapsuleTemplateStore,
 
 	songStore types.SongStore,
 	questionAnswerStore types.QuestionAnswerStore,
 	writingStore types.WritingStore,
+	letterStore types.LetterStore,
 	photoStore types.PhotoStore,
 	audioStore types.AudioStore,
 	doodleStore types.DoodleStore,
 	miscFileStore types.MiscFileStore,
 ) *Handler {
 	return &Handler{
-		capsuleStore: capsuleStore,
-		userStore:    userStore,
-		fileStore:    fileStore,
+		capsuleStore:     capsuleStore,
+		userStore:        userStore,
+		fileStore:        fileStore,
+		joinRequestStore: joinRequestStore,
+		mailer:           mailer,
+		pusher:           pusher,
+		preferenceStore:  preferenceStore,
+		clock:            clock,
+		giftStore:        giftStore,
+		templateStore:    templateStore,
 
 		songStore:           songStore,
 		questionAnswerStore: questionAnswerStore,
 		writingStore:        writingStore,
+		letterStore:         letterStore,
 		photoStore:          photoStore,
 		audioStore:          audioStore,
 		doodleStore:         doodleStore,
@@ -59,13 +90,31 @@ func (handler *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/capsules", auth.WithJWTAuth(handler.handleGetCapsules, handler.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/capsules/get-by-id/{capsuleId}", auth.WithJWTAuth(handler.handleGetCapsuleById, handler.userStore)).Methods(http.MethodGet)
 	router.HandleFunc("/capsules/create", auth.WithJWTAuth(handler.handleCreateCapsule, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/create-from-template", auth.WithJWTAuth(handler.handleCreateCapsuleFromTemplate, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/join", auth.WithJWTAuth(handler.handleJoinCapsule, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/share/{capsuleId}", auth.WithJWTAuth(handler.handleGetShareLink, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/capsules/share/{capsuleId}/qr", auth.WithJWTAuth(handler.handleGetShareQRCode, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/capsules/preview/{code}", handler.handleGetCapsulePreview).Methods(http.MethodGet)
+	router.HandleFunc("/capsules/gift", handler.handleGetGiftCapsule).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/code/regenerate", auth.WithJWTAuth(handler.handleRegenerateCapsuleCode, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/code/settings", auth.WithJWTAuth(handler.handleUpdateCapsuleCodeSettings, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/delete", auth.WithJWTAuth(handler.handleDeleteCapsule, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/name", auth.WithJWTAuth(handler.handleNameCapsule, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/seal", auth.WithJWTAuth(handler.handleSealCapsule, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/deadline", auth.WithJWTAuth(handler.handleSetContributionDeadline, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/member-seal", auth.WithJWTAuth(handler.handleMemberSealCapsule, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/outstanding/{capsuleId}", auth.WithJWTAuth(handler.handleGetOutstandingCapsuleMembers, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/capsules/nudge", auth.WithJWTAuth(handler.handleNudgeCapsuleMembers, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/member-role", auth.WithJWTAuth(handler.handleSetCapsuleMemberRole, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/leave", auth.WithJWTAuth(handler.handleLeaveCapsule, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/remove-member", auth.WithJWTAuth(handler.handleRemoveCapsuleMember, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/transfer-ownership", auth.WithJWTAuth(handler.handleTransferCapsuleOwnership, handler.userStore)).Methods(http.MethodPost)
 	router.HandleFunc("/capsules/open", auth.WithJWTAuth(handler.handleOpenCapsule, handler.userStore)).Methods(http.MethodPost)
-	router.HandleFunc("/capsules/send-reminder-mail", handler.handleSendReminderMail).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/surprise", auth.WithJWTAuth(handler.handleSetCapsuleSurprise, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/open-policy", auth.WithJWTAuth(handler.handleSetOpenPolicy, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/recurrence", auth.WithJWTAuth(handler.handleSetCapsuleRecurrence, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/capsules/series/{capsuleId}", auth.WithJWTAuth(handler.handleGetCapsuleSeries, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/capsules/send-reminder-mail", auth.WithAdminAPIKey(handler.handleSendReminderMail)).Methods(http.MethodPost)
 }
 
 func (handler *Handler) handleGetCapsules(w http.ResponseWriter, r *http.Request) {
@@ -94,67 +143,838 @@ func (handler *Handler) handleGetCapsuleById(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	capsule, err := handler.capsuleStore.GetCapsuleByIdUnsafe(userID, uint(capsuleId))
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionView)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if capsule.Sealed == "sealed" {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("capsule is sealed"))
+		return
+	}
+
+	response, err := handler.getCapsuleContents(capsule, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// getCapsuleContents loads everything in the capsule that the user can see
+func (handler *Handler) getCapsuleContents(capsule types.Capsule, userID uint) (types.GetCapsuleByIdResponse, error) {
+	var response types.GetCapsuleByIdResponse
+
+	songs, err := handler.songStore.GetSongs(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	questionAnswers, err := handler.questionAnswerStore.GetQuestionAnswers(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	writings, err := handler.writingStore.GetWritings(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	letters, err := handler.letterStore.GetLetters(capsule.ID, userID, handler.clock.Now())
+	if err != nil {
+		return response, err
+	}
+	photos, err := handler.photoStore.GetPhotos(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	audios, err := handler.audioStore.GetAudios(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	doodles, err := handler.doodleStore.GetDoodles(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+	miscFiles, err := handler.miscFileStore.GetMiscFiles(capsule.ID, userID)
+	if err != nil {
+		return response, err
+	}
+
+	response = types.GetCapsuleByIdResponse{
+		Capsule:         capsule,
+		Songs:           songs,
+		QuestionAnswers: questionAnswers,
+		Writings:        writings,
+		Letters:         letters,
+		Photos:          photos,
+		Audios:          audios,
+		Doodles:         doodles,
+		MiscFiles:       miscFiles,
+	}
+
+	// the stores only returned the user's own contributions, let them know how much else is in there
+	if capsule.Surprise && capsule.Sealed != "opened" {
+		counts, err := handler.capsuleStore.GetHiddenContributionCounts(capsule.ID, userID)
+		if err != nil {
+			return response, err
+		}
+		response.HiddenContributions = &counts
+	}
+
+	return response, nil
+}
+
+// handleGetGiftCapsule shows an opened capsule to a gift recipient from the link they were sent, no account needed
+func (handler *Handler) handleGetGiftCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.GiftTokenPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	recipientID, err := auth.VerifyGiftToken(payload.Token)
+	if err != nil {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+	recipient, err := handler.giftStore.GetGiftRecipientById(recipientID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	capsule, err := handler.capsuleStore.GetOpenedCapsuleById(recipient.CapsuleID)
+	if err != nil {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	// the recipient isn't a member, so they only see what everyone shared
+	response, err := handler.getCapsuleContents(capsule, 0)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (handler *Handler) handleCreateCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.CreateCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	memberLimit, err := capsuleMemberLimit(payload.MemberLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsuleID, err := handler.capsuleStore.CreateCapsule(userID, payload.Vessel, payload.Public, memberLimit, payload.Surprise)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": capsuleID})
+}
+
+func (handler *Handler) handleCreateCapsuleFromTemplate(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.CreateCapsuleFromTemplatePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	memberLimit, err := capsuleMemberLimit(payload.MemberLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	template, err := handler.templateStore.GetCapsuleTemplateById(userID, payload.TemplateID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	capsuleID, err := handler.capsuleStore.CreateCapsuleFromTemplate(userID, *template, payload.Public, memberLimit, payload.Surprise)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": capsuleID})
+}
+
+// capsuleMemberLimit fills in the default member limit and checks it isn't over the maximum
+func capsuleMemberLimit(memberLimit uint) (uint, error) {
+	if memberLimit == 0 {
+		memberLimit = uint(config.Envs.DefaultCapsuleMemberLimit)
+	}
+	if memberLimit > uint(config.Envs.MaxCapsuleMemberLimit) {
+		return 0, fmt.Errorf("member limit cannot exceed %d", config.Envs.MaxCapsuleMemberLimit)
+	}
+	return memberLimit, nil
+}
+
+func (handler *Handler) handleJoinCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.JoinCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.GetCapsuleByCode(payload.Code)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = checkCapsuleCode(capsule, handler.clock.Now())
+	if err != nil {
+		utils.WriteError(w, capsuleCodeErrorStatus(err), err)
+		return
+	}
+
+	// private capsules can only be joined once the owner approves
+	if !capsule.Public {
+		if findCapsuleMember(capsule, userID) != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you are already a member of the capsule"))
+			return
+		}
+
+		// the request is made first so asking twice doesn't use up the code
+		joinRequestID, err := handler.joinRequestStore.CreateJoinRequest(capsule.ID, userID)
+		if errors.Is(err, joinRequest.ErrAlreadyRequested) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		err = handler.capsuleStore.UseCapsuleCode(capsule.ID)
+		if err != nil {
+			// someone else took the last use in the meantime
+			if rejectErr := handler.joinRequestStore.UpdateJoinRequestStatus(joinRequestID, "rejected"); rejectErr != nil {
+				log.Printf("error rejecting join request %d: %v", joinRequestID, rejectErr)
+			}
+			utils.WriteError(w, capsuleCodeErrorStatus(err), err)
+			return
+		}
+		utils.WriteJSON(w, http.StatusAccepted, map[string]uint{"joinRequestId": joinRequestID})
+		return
+	}
+
+	err = handler.capsuleStore.JoinCapsule(userID, payload.Code)
+	if err != nil {
+		utils.WriteError(w, capsuleCodeErrorStatus(err), err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// capsuleCodeErrorStatus picks the status code for a failed join, so the app can tell why a code no longer works
+func capsuleCodeErrorStatus(err error) int {
+	switch err {
+	case ErrCapsuleCodeRevoked:
+		return http.StatusForbidden
+	case ErrCapsuleCodeExpired, ErrCapsuleCodeExhausted:
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// shareLink builds the signed deep link that the app resolves through /capsules/preview
+func shareLink(code string) string {
+	return fmt.Sprintf("%s/join/%s?sig=%s", config.Envs.PublicHost, code, auth.SignLink(code))
+}
+
+// getSharedCapsule fetches the capsule from the route if the user is allowed to share it
+func (handler *Handler) getSharedCapsule(r *http.Request) (types.Capsule, int, error) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		return types.Capsule{}, http.StatusBadRequest, fmt.Errorf("capsuleId not provided")
+	}
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		return types.Capsule{}, http.StatusBadRequest, fmt.Errorf("invalid capsuleId")
+	}
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionManageMembers)
+	if err != nil {
+		return capsule, utils.AuthorizeErrorStatus(err), err
+	}
+	return capsule, http.StatusOK, nil
+}
+
+func (handler *Handler) handleGetShareLink(w http.ResponseWriter, r *http.Request) {
+	capsule, status, err := handler.getSharedCapsule(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"code": capsule.Code, "url": shareLink(capsule.Code)})
+}
+
+func (handler *Handler) handleGetShareQRCode(w http.ResponseWriter, r *http.Request) {
+	capsule, status, err := handler.getSharedCapsule(r)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	qrCode, err := qrcode.New(shareLink(capsule.Code), qrcode.Medium)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "png":
+		png, err := qrCode.PNG(512)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Add("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		w.Write(png)
+	case "svg":
+		w.Header().Add("Content-Type", "image/svg+xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(qrCodeSVG(qrCode.Bitmap())))
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("format must be png or svg"))
+	}
+}
+
+// qrCodeSVG draws each dark module of the qr code as a unit square
+func qrCodeSVG(bitmap [][]bool) string {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	size := len(bitmap)
+	return fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges"><rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		size, size, size, size, path.String(),
+	)
+}
+
+func (handler *Handler) handleGetCapsulePreview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	code, ok := vars["code"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("code not provided"))
+		return
+	}
+	if !auth.VerifyLink(code, r.URL.Query().Get("sig")) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("invalid link"))
+		return
+	}
+
+	capsule, err := handler.capsuleStore.GetCapsuleByCode(code)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	err = checkCapsuleCode(capsule, handler.clock.Now())
+	if err != nil {
+		utils.WriteError(w, capsuleCodeErrorStatus(err), err)
+		return
+	}
+
+	ownerName, err := handler.userStore.GetUserNameById(capsule.CapsuleOwnerID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.CapsulePreview{
+		Name:             capsule.Name,
+		Vessel:           capsule.Vessel,
+		OwnerName:        ownerName,
+		RequiresApproval: !capsule.Public,
+	})
+}
+
+func (handler *Handler) handleRegenerateCapsuleCode(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RegenerateCapsuleCodePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	code, err := handler.capsuleStore.RegenerateCapsuleCode(payload.CapsuleID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"code": code})
+}
+
+func (handler *Handler) handleUpdateCapsuleCodeSettings(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.UpdateCapsuleCodeSettingsPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	err = handler.capsuleStore.UpdateCapsuleCodeSettings(payload.CapsuleID, payload.ExpiresAt, payload.MaxUses, payload.Revoked)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleDeleteCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.DeleteCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionDelete)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	objectNames, err := handler.capsuleStore.DeleteCapsule(userID, payload.CapsuleID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, objectName := range objectNames {
+		err = handler.fileStore.DeleteFile(objectName)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleNameCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.NameCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionRename)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	err = handler.capsuleStore.NameCapsule(payload.CapsuleID, payload.Name)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleSealCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.SealCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionSeal)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	// plain dates open at midnight where the capsule is, not midnight UTC
+	timezone := capsule.Timezone
+	if payload.Timezone != "" {
+		timezone = payload.Timezone
+	}
+	dateToOpen, err := utils.ParseDateToOpen(payload.DateToOpen, timezone)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// viewers can't add content, so they don't need to agree to seal
+	for _, member := range capsule.Members {
+		if member.UserID == userID || member.Role == types.CapsuleRoleOwner || member.Role == types.CapsuleRoleViewer {
+			continue
+		}
+		if member.SealedAt == nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("all members must seal the capsule before the owner can seal it"))
+			return
+		}
+	}
+
+	err = handler.capsuleStore.SealCapsule(userID, payload.CapsuleID, dateToOpen, timezone)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleSetContributionDeadline(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.SetContributionDeadlinePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionSeal)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if capsule.Sealed != "preseal" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule has already been sealed"))
+		return
+	}
+	if capsule.ContributionDeadline != nil && !capsule.ContributionDeadline.After(handler.clock.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("contribution deadline has already passed"))
+		return
+	}
+
+	timezone := capsule.Timezone
+	if payload.Timezone != "" {
+		timezone = payload.Timezone
+	}
+	var dateToOpen *time.Time
+	if payload.DateToOpen != "" {
+		date, err := utils.ParseDateToOpen(payload.DateToOpen, timezone)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		dateToOpen = &date
+	}
+	if payload.ContributionDeadline != nil {
+		if !payload.ContributionDeadline.After(handler.clock.Now()) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("contribution deadline must be in the future"))
+			return
+		}
+		if !dateToOpen.After(*payload.ContributionDeadline) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule must open after the contribution deadline"))
+			return
+		}
+	}
+
+	err = handler.capsuleStore.SetContributionDeadline(payload.CapsuleID, payload.ContributionDeadline, dateToOpen, timezone)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	if capsule.Sealed == "sealed" {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("capsule is sealed"))
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleMemberSealCapsule(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.MemberSealCapsulePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
 		return
 	}
 
-	songs, err := handler.songStore.GetSongs(uint(capsule.ID))
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	if capsule.CapsuleOwnerID == userID {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are the owner of the capsule, you cannot seal the capsule as a member"))
 		return
 	}
-	questionAnswers, err := handler.questionAnswerStore.GetQuestionAnswers(uint(capsule.ID))
+
+	member := findCapsuleMember(capsule, userID)
+	if member.SealedAt != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
+		return
+	}
+
+	err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	writings, err := handler.writingStore.GetWritings(uint(capsule.ID))
+	handler.notifyOwnerMemberSealed(capsule, userID)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// notifyOwnerMemberSealed lets the owner know a member is done, failing to do so shouldn't undo the seal
+func (handler *Handler) notifyOwnerMemberSealed(capsule types.Capsule, userId uint) {
+	owner, err := handler.userStore.GetUserById(capsule.CapsuleOwnerID)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		log.Printf("error fetching owner of capsule %d: %v", capsule.ID, err)
 		return
 	}
-	photos, err := handler.photoStore.GetPhotos(uint(capsule.ID))
+	memberName, err := handler.userStore.GetUserNameById(userId)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		log.Printf("error fetching user %d for capsule %d: %v", userId, capsule.ID, err)
 		return
 	}
-	audios, err := handler.audioStore.GetAudios(uint(capsule.ID))
+
+	if err := handler.pusher.Push(owner.ID, push.MemberSealed(capsule.ID, capsule.Name, memberName)); err != nil {
+		log.Printf("error pushing to owner of capsule %d: %v", capsule.ID, err)
+	}
+
+	mailEnabled, err := handler.preferenceStore.IsNotificationEnabled(owner.ID, types.NotificationChannelEmail, types.NotificationEventMemberSealed)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		log.Printf("error fetching notification preferences of user %d: %v", owner.ID, err)
+		return
+	}
+	if !mailEnabled {
+		return
+	}
+
+	data := mail.CapsuleTemplateData(capsule.ID, capsule.Name, capsule.Vessel, capsule.DateToOpen, capsule.Timezone)
+	data.RecipientName = owner.Name
+	data.ActorName = memberName
+	data.UnsubscribeLink = mail.UnsubscribeLink(owner.ID, types.NotificationEventMemberSealed)
+	sealedMail, err := mail.RenderMail([]string{owner.Email}, mail.TemplateMemberSealed, data)
+	if err != nil {
+		log.Printf("error rendering sealed mail for capsule %d: %v", capsule.ID, err)
+		return
+	}
+	if err := handler.mailer.Send(sealedMail); err != nil {
+		log.Printf("error notifying owner of capsule %d: %v", capsule.ID, err)
+	}
+}
+
+func (handler *Handler) handleGetOutstandingCapsuleMembers(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsuleId not provided"))
+		return
+	}
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid capsuleId"))
+		return
+	}
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionView)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
-	doodles, err := handler.doodleStore.GetDoodles(uint(capsule.ID))
+
+	members, err := handler.capsuleStore.GetOutstandingCapsuleMembers(uint(capsuleId))
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	miscFiles, err := handler.miscFileStore.GetMiscFiles(uint(capsule.ID))
+	utils.WriteJSON(w, http.StatusOK, members)
+}
+
+func (handler *Handler) handleNudgeCapsuleMembers(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.NudgeCapsuleMembersPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if capsule.Sealed != "preseal" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule has already been sealed"))
+		return
+	}
+
+	members, err := handler.capsuleStore.NudgeCapsuleMembers(payload.CapsuleID, payload.UserID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, types.GetCapsuleByIdResponse{
-		Capsule:         capsule,
-		Songs:           songs,
-		QuestionAnswers: questionAnswers,
-		Writings:        writings,
-		Photos:          photos,
-		Audios:          audios,
-		Doodles:         doodles,
-		MiscFiles:       miscFiles,
-	})
+	// asking for one member should say why they weren't nudged
+	if payload.UserID != 0 {
+		var member *types.OutstandingCapsuleMember
+		for i := range members {
+			if members[i].UserID == payload.UserID {
+				member = &members[i]
+			}
+		}
+		if member == nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user is not waiting to seal the capsule"))
+			return
+		}
+		if !member.Nudged {
+			utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("user was nudged recently, try again after %s", member.NextNudgeAt.Format(time.RFC3339)))
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, members)
 }
 
-func (handler *Handler) handleCreateCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleSetCapsuleMemberRole(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.CreateCapsulePayload
+	var payload types.SetCapsuleMemberRolePayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -170,17 +990,33 @@ func (handler *Handler) handleCreateCapsule(w http.ResponseWriter, r *http.Reque
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsuleID, err := handler.capsuleStore.CreateCapsule(userID, payload.Vessel, payload.Public)
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	member := findCapsuleMember(capsule, payload.UserID)
+	if member == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user is not a member of the capsule"))
+		return
+	}
+	if member.Role == types.CapsuleRoleOwner {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the owner's role cannot be changed"))
+		return
+	}
+
+	err = handler.capsuleStore.SetCapsuleMemberRole(payload.CapsuleID, payload.UserID, payload.Role)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": capsuleID})
+	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
-func (handler *Handler) handleJoinCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleLeaveCapsule(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.JoinCapsulePayload
+	var payload types.LeaveCapsulePayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -196,7 +1032,22 @@ func (handler *Handler) handleJoinCapsule(w http.ResponseWriter, r *http.Request
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	err = handler.capsuleStore.JoinCapsule(userID, payload.Code)
+	// owners don't have the leave permission, so check for them before it to explain why
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionView)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if capsule.Role == types.CapsuleRoleOwner {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you must transfer ownership of the capsule before leaving it"))
+		return
+	}
+	if err := checkPermission(capsule, types.CapsulePermissionLeave); err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	err = handler.removeCapsuleMember(payload.CapsuleID, userID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -204,9 +1055,9 @@ func (handler *Handler) handleJoinCapsule(w http.ResponseWriter, r *http.Request
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
-func (handler *Handler) handleDeleteCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleRemoveCapsuleMember(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.DeleteCapsulePayload
+	var payload types.RemoveCapsuleMemberPayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -222,36 +1073,49 @@ func (handler *Handler) handleDeleteCapsule(w http.ResponseWriter, r *http.Reque
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsule, err := handler.capsuleStore.GetCapsuleByIdUnsafe(userID, payload.CapsuleID)
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
-	if capsule.CapsuleOwnerID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are not the owner of the capsule"))
+
+	member := findCapsuleMember(capsule, payload.UserID)
+	if member == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user is not a member of the capsule"))
+		return
+	}
+	if member.Role == types.CapsuleRoleOwner {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the owner cannot be removed from the capsule"))
 		return
 	}
 
-	objectNames, err := handler.capsuleStore.DeleteCapsule(userID, payload.CapsuleID)
+	err = handler.removeCapsuleMember(payload.CapsuleID, payload.UserID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// removeCapsuleMember drops the member and their contributions, including uploaded files
+func (handler *Handler) removeCapsuleMember(capsuleId uint, userId uint) error {
+	objectNames, err := handler.capsuleStore.RemoveCapsuleMember(capsuleId, userId)
+	if err != nil {
+		return err
+	}
 
 	for _, objectName := range objectNames {
 		err = handler.fileStore.DeleteFile(objectName)
 		if err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, err)
-			return
+			return err
 		}
 	}
-
-	utils.WriteJSON(w, http.StatusOK, nil)
+	return nil
 }
 
-func (handler *Handler) handleNameCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleTransferCapsuleOwnership(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.NameCapsulePayload
+	var payload types.TransferCapsuleOwnershipPayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -267,17 +1131,22 @@ func (handler *Handler) handleNameCapsule(w http.ResponseWriter, r *http.Request
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsule, err := handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionTransfer)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
-	if capsule.CapsuleOwnerID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are not the owner of the capsule"))
+
+	if payload.UserID == userID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you are already the owner of the capsule"))
+		return
+	}
+	if findCapsuleMember(capsule, payload.UserID) == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user is not a member of the capsule"))
 		return
 	}
 
-	err = handler.capsuleStore.NameCapsule(userID, payload.CapsuleID, payload.Name)
+	err = handler.capsuleStore.TransferCapsuleOwnership(payload.CapsuleID, userID, payload.UserID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -285,9 +1154,9 @@ func (handler *Handler) handleNameCapsule(w http.ResponseWriter, r *http.Request
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
-func (handler *Handler) handleSealCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleOpenCapsule(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.SealCapsulePayload
+	var payload types.OpenCapsulePayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -301,48 +1170,64 @@ func (handler *Handler) handleSealCapsule(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dateToOpen, err := time.Parse("2006-01-02", payload.DateToOpen)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid date to open the capsule"))
-		return
-	}
-
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsule, err := handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	// who else can open it depends on the capsule's open policy
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionView)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
-	if capsule.CapsuleOwnerID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are not the owner of the capsule"))
+	if capsule.Sealed != "sealed" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule is not currently sealed"))
 		return
 	}
-	if capsule.Sealed != "preseal" {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule has already been sealed (or opened)"))
+
+	curDate := handler.clock.Now()
+	if curDate.Before(*capsule.DateToOpen) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid date to open the capsule"))
 		return
 	}
 
-	if (capsule.CapsuleMember1ID != 0 && !capsule.CapsuleMember1Sealed) ||
-		(capsule.CapsuleMember2ID != 0 && !capsule.CapsuleMember2Sealed) ||
-		(capsule.CapsuleMember3ID != 0 && !capsule.CapsuleMember3Sealed) ||
-		(capsule.CapsuleMember4ID != 0 && !capsule.CapsuleMember4Sealed) ||
-		(capsule.CapsuleMember5ID != 0 && !capsule.CapsuleMember5Sealed) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("all members must seal the capsule before the owner can seal it"))
+	switch capsule.OpenPolicy {
+	case types.CapsuleOpenPolicyQuorum:
+		response, err := handler.capsuleStore.VoteToOpenCapsule(userID, payload.CapsuleID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if response.Opened {
+			handler.createNextEdition(payload.CapsuleID)
+		}
+		utils.WriteJSON(w, http.StatusOK, response)
 		return
+	case types.CapsuleOpenPolicyOwner:
+		if err := checkPermission(capsule, types.CapsulePermissionOpen); err != nil {
+			utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+			return
+		}
 	}
 
-	err = handler.capsuleStore.SealCapsule(userID, payload.CapsuleID, dateToOpen)
+	err = handler.capsuleStore.OpenCapsule(payload.CapsuleID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, nil)
+	handler.createNextEdition(payload.CapsuleID)
+	utils.WriteJSON(w, http.StatusOK, types.OpenCapsuleResponse{Opened: true})
 }
 
-func (handler *Handler) handleMemberSealCapsule(w http.ResponseWriter, r *http.Request) {
+// createNextEdition starts the next edition of a recurring capsule right away, if it fails the scheduler tries again
+func (handler *Handler) createNextEdition(capsuleId uint) {
+	_, err := handler.capsuleStore.CreateNextEdition(capsuleId)
+	if err != nil {
+		log.Printf("error creating the next edition of capsule %d: %v", capsuleId, err)
+	}
+}
+
+func (handler *Handler) handleSetCapsuleRecurrence(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.OpenCapsulePayload
+	var payload types.SetCapsuleRecurrencePayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -358,65 +1243,55 @@ func (handler *Handler) handleMemberSealCapsule(w http.ResponseWriter, r *http.R
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsule, err := handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionSetRecurrence)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
 
-	if capsule.CapsuleOwnerID == userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are the owner of the capsule, you cannot seal the capsule as a member"))
-		return
+	var recurrenceUnit *string
+	if payload.RecurrenceUnit != "" {
+		recurrenceUnit = &payload.RecurrenceUnit
 	}
-	if capsule.Sealed != "preseal" {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule has already been sealed (or opened)"))
+	err = handler.capsuleStore.SetCapsuleRecurrence(payload.CapsuleID, recurrenceUnit, payload.RecurrenceInterval, payload.NamePattern)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	if capsule.CapsuleMember1ID == userID {
-		if capsule.CapsuleMember1Sealed {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
-			return
-		}
-		err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID, 1)
-	}
-	if capsule.CapsuleMember2ID == userID {
-		if capsule.CapsuleMember2Sealed {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
-			return
-		}
-		err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID, 2)
-	}
-	if capsule.CapsuleMember3ID == userID {
-		if capsule.CapsuleMember3Sealed {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
-			return
-		}
-		err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID, 3)
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleGetCapsuleSeries(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsuleId not provided"))
+		return
 	}
-	if capsule.CapsuleMember4ID == userID {
-		if capsule.CapsuleMember4Sealed {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
-			return
-		}
-		err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID, 4)
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid capsuleId"))
+		return
 	}
-	if capsule.CapsuleMember5ID == userID {
-		if capsule.CapsuleMember5Sealed {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you have already sealed the capsule"))
-			return
-		}
-		err = handler.capsuleStore.MemberSealCapsule(userID, payload.CapsuleID, 5)
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionView)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
 	}
+
+	editions, err := handler.capsuleStore.GetCapsuleSeries(userID, uint(capsuleId))
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, nil)
+	utils.WriteJSON(w, http.StatusOK, map[string][]types.CapsuleEdition{"editions": editions})
 }
 
-func (handler *Handler) handleOpenCapsule(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) handleSetCapsuleSurprise(w http.ResponseWriter, r *http.Request) {
 	// get json payload
-	var payload types.OpenCapsulePayload
+	var payload types.SetCapsuleSurprisePayload
 	err := utils.ParseJSON(r, &payload)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -432,31 +1307,49 @@ func (handler *Handler) handleOpenCapsule(w http.ResponseWriter, r *http.Request
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	capsule, err := handler.capsuleStore.GetCapsuleByIdUnsafe(userID, payload.CapsuleID)
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionSetSurprise)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
-	if capsule.CapsuleOwnerID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are not the owner of the capsule"))
+
+	err = handler.capsuleStore.SetCapsuleSurprise(payload.CapsuleID, payload.Surprise)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	if capsule.CapsuleOwnerID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you are not the owner of the capsule"))
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleSetOpenPolicy(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.SetOpenPolicyPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
-	if capsule.Sealed != "sealed" {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule is not currently sealed"))
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
 		return
 	}
 
-	curDate := time.Now()
-	if curDate.Before(*capsule.DateToOpen) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid date to open the capsule"))
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionSetOpenPolicy)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if capsule.Sealed == "opened" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsule has already been opened"))
 		return
 	}
 
-	err = handler.capsuleStore.OpenCapsule(userID, payload.CapsuleID)
+	err = handler.capsuleStore.SetOpenPolicy(payload.CapsuleID, payload.OpenPolicy, payload.OpenQuorum)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -465,11 +1358,6 @@ func (handler *Handler) handleOpenCapsule(w http.ResponseWriter, r *http.Request
 }
 
 func (handler *Handler) handleSendReminderMail(w http.ResponseWriter, r *http.Request) {
-	if config.Envs.AdminAPIKey != r.Header.Get("AdminAPIKey") {
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid admin api key"))
-		return
-	}
-
 	err := handler.capsuleStore.SendReminderMail()
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
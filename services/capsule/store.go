@@ -1,95 +1,135 @@
 package capsule
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
-	"math/rand"
-	"net/smtp"
-	"strings"
+	"log"
+	"math/big"
 	"time"
 
-	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
 	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
 )
 
 type CapsuleStore struct {
-	db  *sql.DB
-	rng *rand.Rand
+	db          *sql.DB
+	outboxStore types.OutboxStore
+	clock       types.Clock // capsule lifecycle times come from here rather than the database's NOW()
 }
 
-func NewCapsuleStore(db *sql.DB) *CapsuleStore {
+func NewCapsuleStore(db *sql.DB, outboxStore types.OutboxStore, clock types.Clock) *CapsuleStore {
 	return &CapsuleStore{
-		db:  db,
-		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+		db:          db,
+		outboxStore: outboxStore,
+		clock:       clock,
 	}
 }
 
+var (
+	ErrCapsuleCodeRevoked   = errors.New("joining this capsule by code has been disabled")
+	ErrCapsuleCodeExpired   = errors.New("this capsule code has expired")
+	ErrCapsuleCodeExhausted = errors.New("this capsule code has reached its maximum number of uses")
+)
+
 func scanRowIntoCapsule(row *sql.Rows) (*types.Capsule, error) {
 	capsule := new(types.Capsule)
 
-	var capsuleMember1Id, capsuleMember2Id, capsuleMember3Id, capsuleMember4Id, capsuleMember5Id sql.NullInt64
-
 	err := row.Scan(
 		&capsule.ID,
 		&capsule.Code,
 		&capsule.CreatedAt,
 		&capsule.Public,
 		&capsule.CapsuleOwnerID,
-		&capsuleMember1Id,
-		&capsuleMember2Id,
-		&capsuleMember3Id,
-		&capsuleMember4Id,
-		&capsuleMember5Id,
-		&capsule.CapsuleMember1Sealed,
-		&capsule.CapsuleMember2Sealed,
-		&capsule.CapsuleMember3Sealed,
-		&capsule.CapsuleMember4Sealed,
-		&capsule.CapsuleMember5Sealed,
 		&capsule.Vessel,
 		&capsule.Name,
 		&capsule.DateToOpen,
-		&capsule.EmailSent,
 		&capsule.Sealed,
+		&capsule.MemberLimit,
+		&capsule.CodeExpiresAt,
+		&capsule.CodeMaxUses,
+		&capsule.CodeUses,
+		&capsule.CodeRevoked,
+		&capsule.OpenedAt,
+		&capsule.ContributionDeadline,
+		&capsule.Timezone,
+		&capsule.OpenPolicy,
+		&capsule.OpenQuorum,
+		&capsule.Surprise,
+		&capsule.RecurrenceUnit,
+		&capsule.RecurrenceInterval,
+		&capsule.NamePattern,
+		&capsule.SeriesID,
+		&capsule.Edition,
+		&capsule.RenewedAt,
+		&capsule.TemplateID,
+		&capsule.DeadlineSealedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	capsule.LocalDateToOpen = utils.LocalTime(capsule.DateToOpen, capsule.Timezone)
 
-	// Set CapsuleMemberID fields to 0 if they are NULL
-	if capsuleMember1Id.Valid {
-		capsule.CapsuleMember1ID = uint(capsuleMember1Id.Int64)
-	} else {
-		capsule.CapsuleMember1ID = 0
-	}
-	if capsuleMember2Id.Valid {
-		capsule.CapsuleMember2ID = uint(capsuleMember2Id.Int64)
-	} else {
-		capsule.CapsuleMember2ID = 0
+	return capsule, nil
+}
+
+func scanRowIntoCapsuleMember(row *sql.Rows) (*types.CapsuleMember, error) {
+	member := new(types.CapsuleMember)
+
+	err := row.Scan(
+		&member.ID,
+		&member.CapsuleID,
+		&member.UserID,
+		&member.Role,
+		&member.SealedAt,
+		&member.JoinedAt,
+		&member.LastNudgedAt,
+		&member.OpenVotedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
-	if capsuleMember3Id.Valid {
-		capsule.CapsuleMember3ID = uint(capsuleMember3Id.Int64)
-	} else {
-		capsule.CapsuleMember3ID = 0
+
+	return member, nil
+}
+
+// findCapsuleMember returns the membership of the user in the capsule, or nil if they are not a member
+func findCapsuleMember(capsule types.Capsule, userId uint) *types.CapsuleMember {
+	for i := range capsule.Members {
+		if capsule.Members[i].UserID == userId {
+			return &capsule.Members[i]
+		}
 	}
-	if capsuleMember4Id.Valid {
-		capsule.CapsuleMember4ID = uint(capsuleMember4Id.Int64)
-	} else {
-		capsule.CapsuleMember4ID = 0
+	return nil
+}
+
+func (capsuleStore *CapsuleStore) getCapsuleMembers(capsuleId uint) ([]types.CapsuleMember, error) {
+	rows, err := capsuleStore.db.Query("SELECT * FROM capsuleMembers WHERE capsuleId = ? ORDER BY joinedAt, id", capsuleId)
+	if err != nil {
+		return nil, err
 	}
-	if capsuleMember5Id.Valid {
-		capsule.CapsuleMember5ID = uint(capsuleMember5Id.Int64)
-	} else {
-		capsule.CapsuleMember5ID = 0
+	defer rows.Close()
+
+	members := make([]types.CapsuleMember, 0)
+	for rows.Next() {
+		member, err := scanRowIntoCapsuleMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *member)
 	}
 
-	return capsule, nil
+	return members, rows.Err()
 }
 
 func (capsuleStore *CapsuleStore) GetCapsules(userId uint) ([]types.Capsule, error) {
-	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE capsuleOwnerId = ? OR capsuleMember1Id = ? OR capsuleMember2Id = ? OR capsuleMember3Id = ? OR capsuleMember4Id = ? OR capsuleMember5Id = ?", userId, userId, userId, userId, userId, userId)
+	rows, err := capsuleStore.db.Query("SELECT c.* FROM capsules c JOIN capsuleMembers m ON m.capsuleId = c.id WHERE m.userId = ?", userId)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	capsules := make([]types.Capsule, 0)
 	for rows.Next() {
@@ -99,10 +139,24 @@ func (capsuleStore *CapsuleStore) GetCapsules(userId uint) ([]types.Capsule, err
 		}
 		capsules = append(capsules, *capsule)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range capsules {
+		capsules[i].Members, err = capsuleStore.getCapsuleMembers(capsules[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		if member := findCapsuleMember(capsules[i], userId); member != nil {
+			capsules[i].Role = member.Role
+		}
+	}
 
 	return capsules, nil
 }
 
+// GetCapsuleById fetches the capsule for one of its members, AuthorizeCapsule also checks what they're allowed to do
 func (capsuleStore *CapsuleStore) GetCapsuleById(userId uint, capsuleId uint) (types.Capsule, error) {
 	capsule := new(types.Capsule)
 	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE id = ?", capsuleId)
@@ -118,76 +172,82 @@ func (capsuleStore *CapsuleStore) GetCapsuleById(userId uint, capsuleId uint) (t
 	}
 
 	if capsule.ID != capsuleId {
-		return *capsule, fmt.Errorf("capsule not found")
+		return *capsule, types.ErrCapsuleNotFound
 	}
-	if capsule.CapsuleOwnerID != userId && capsule.CapsuleMember1ID != userId && capsule.CapsuleMember2ID != userId && capsule.CapsuleMember3ID != userId && capsule.CapsuleMember4ID != userId && capsule.CapsuleMember5ID != userId {
-		return *capsule, fmt.Errorf("user is not authorized to view this capsule")
+	capsule.Members, err = capsuleStore.getCapsuleMembers(capsule.ID)
+	if err != nil {
+		return *capsule, err
 	}
-	if capsule.Sealed == "sealed" || capsule.Sealed == "opened" {
-		return *capsule, fmt.Errorf("capsule cannot be modified because it has already been sealed or opened")
+	member := findCapsuleMember(*capsule, userId)
+	if member == nil {
+		return *capsule, fmt.Errorf("%w: user is not authorized to view this capsule", types.ErrCapsulePermissionDenied)
 	}
+	capsule.Role = member.Role
 
 	return *capsule, nil
 }
 
-func (capsuleStore *CapsuleStore) GetCapsuleByIdUnsafe(userId uint, capsuleId uint) (types.Capsule, error) {
-	capsule := new(types.Capsule)
-	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE id = ?", capsuleId)
+// AuthorizeCapsule fetches the capsule and checks that the user's role allows the permission in the capsule's current state
+func (capsuleStore *CapsuleStore) AuthorizeCapsule(userId uint, capsuleId uint, permission string) (types.Capsule, error) {
+	capsule, err := capsuleStore.GetCapsuleById(userId, capsuleId)
 	if err != nil {
-		return *capsule, err
+		return capsule, err
 	}
 
-	for rows.Next() {
-		capsule, err = scanRowIntoCapsule(rows)
-		if err != nil {
-			return *capsule, err
-		}
-	}
-
-	if capsule.ID != capsuleId {
-		return *capsule, fmt.Errorf("capsule not found")
-	}
-	if capsule.CapsuleOwnerID != userId && capsule.CapsuleMember1ID != userId && capsule.CapsuleMember2ID != userId && capsule.CapsuleMember3ID != userId && capsule.CapsuleMember4ID != userId && capsule.CapsuleMember5ID != userId {
-		return *capsule, fmt.Errorf("user is not authorized to view this capsule")
-	}
-
-	return *capsule, nil
+	return capsule, checkPermission(capsule, permission)
 }
 
-func (capsuleStore *CapsuleStore) GenerateCapsuleCode(length int) string {
+func (capsuleStore *CapsuleStore) GenerateCapsuleCode(length int) (string, error) {
 	const charset = "abcdefghijklmnopqrstuvwxyz" +
 		"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
 		"0123456789"
 
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[capsuleStore.rng.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[n.Int64()]
 	}
-	return string(b)
+	return string(b), nil
 }
 
-func (capsuleStore *CapsuleStore) CreateCapsule(userId uint, vessel string, public bool) (uint, error) {
-	// generate unique capulse code
+func (capsuleStore *CapsuleStore) generateUniqueCapsuleCode() (string, error) {
 	var code string
 	generateCodeAttempts := 0
 	const codeLength = 10
 
 	for {
-		code = capsuleStore.GenerateCapsuleCode(codeLength)
+		var err error
+		code, err = capsuleStore.GenerateCapsuleCode(codeLength)
+		if err != nil {
+			return "", err
+		}
 		var count int
-		err := capsuleStore.db.QueryRow("SELECT COUNT(*) FROM capsules WHERE code = ?", code).Scan(&count)
+		err = capsuleStore.db.QueryRow("SELECT COUNT(*) FROM capsules WHERE code = ?", code).Scan(&count)
 		if err != nil {
-			return 0, err
+			return "", err
 		}
 		if count == 0 {
 			break
 		}
 		if generateCodeAttempts > 10 {
-			return 0, fmt.Errorf("failed to generate unique capsule code after 10 attempts")
+			return "", fmt.Errorf("failed to generate unique capsule code after 10 attempts")
 		}
 		generateCodeAttempts++
 	}
 
+	return code, nil
+}
+
+func (capsuleStore *CapsuleStore) CreateCapsule(userId uint, vessel string, public bool, memberLimit uint, surprise bool) (uint, error) {
+	// generate unique capulse code
+	code, err := capsuleStore.generateUniqueCapsuleCode()
+	if err != nil {
+		return 0, err
+	}
+
 	// check if vessel is valid
 	allowedVessels := []string{"box", "suitcase", "guitar case", "bottle", "shoe", "garbage"}
 	validVessel := false
@@ -201,7 +261,11 @@ func (capsuleStore *CapsuleStore) CreateCapsule(userId uint, vessel string, publ
 		return 0, fmt.Errorf("invalid vessel")
 	}
 
-	res, err := capsuleStore.db.Exec("INSERT INTO capsules (code, capsuleOwnerId, vessel, name, public) VALUES (?, ?, ?, 'My Time Capsule', ?)", code, userId, vessel, public)
+	// capsules start out in their owner's timezone
+	res, err := capsuleStore.db.Exec(
+		"INSERT INTO capsules (code, capsuleOwnerId, vessel, name, public, memberLimit, surprise, timezone) SELECT ?, ?, ?, 'My Time Capsule', ?, ?, ?, timezone FROM users WHERE id = ?",
+		code, userId, vessel, public, memberLimit, surprise, userId,
+	)
 	if err != nil {
 		return 0, err
 	}
@@ -211,9 +275,113 @@ func (capsuleStore *CapsuleStore) CreateCapsule(userId uint, vessel string, publ
 		return 0, err
 	}
 
+	_, err = capsuleStore.db.Exec("INSERT INTO capsuleMembers (capsuleId, userId, role) VALUES (?, ?, 'owner')", id, userId)
+	if err != nil {
+		return 0, err
+	}
+
 	return uint(id), nil
 }
 
+// GetCapsuleByCode looks up a capsule for someone who isn't a member yet, so there is no authorization check
+func (capsuleStore *CapsuleStore) GetCapsuleByCode(code string) (types.Capsule, error) {
+	capsule := new(types.Capsule)
+	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE code = ?", code)
+	if err != nil {
+		return *capsule, err
+	}
+
+	for rows.Next() {
+		capsule, err = scanRowIntoCapsule(rows)
+		if err != nil {
+			return *capsule, err
+		}
+	}
+	if capsule.Code != code {
+		return *capsule, fmt.Errorf("capsule not found")
+	}
+
+	capsule.Members, err = capsuleStore.getCapsuleMembers(capsule.ID)
+	return *capsule, err
+}
+
+// GetOpenedCapsuleById is for gift recipients who aren't members, so it only works once the capsule is opened
+func (capsuleStore *CapsuleStore) GetOpenedCapsuleById(capsuleId uint) (types.Capsule, error) {
+	capsule := new(types.Capsule)
+	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE id = ?", capsuleId)
+	if err != nil {
+		return *capsule, err
+	}
+
+	for rows.Next() {
+		capsule, err = scanRowIntoCapsule(rows)
+		if err != nil {
+			return *capsule, err
+		}
+	}
+	if capsule.ID != capsuleId {
+		return *capsule, fmt.Errorf("capsule not found")
+	}
+	if capsule.Sealed != "opened" {
+		return *capsule, fmt.Errorf("capsule has not been opened yet")
+	}
+
+	capsule.Members, err = capsuleStore.getCapsuleMembers(capsule.ID)
+	return *capsule, err
+}
+
+// checkCapsuleCode reports whether the capsule's code can currently be used to join
+func checkCapsuleCode(capsule types.Capsule, now time.Time) error {
+	if capsule.CodeRevoked {
+		return ErrCapsuleCodeRevoked
+	}
+	if capsule.CodeExpiresAt != nil && now.After(*capsule.CodeExpiresAt) {
+		return ErrCapsuleCodeExpired
+	}
+	if capsule.CodeMaxUses != nil && capsule.CodeUses >= *capsule.CodeMaxUses {
+		return ErrCapsuleCodeExhausted
+	}
+	return nil
+}
+
+// only counts the use if the code has uses left, so concurrent joins can't go over the limit
+const useCapsuleCodeQuery = "UPDATE capsules SET codeUses = codeUses + 1 WHERE id = ? AND (codeMaxUses IS NULL OR codeUses < codeMaxUses)"
+
+// UseCapsuleCode counts a use of the capsule's code, failing if it has run out of uses in the meantime
+func (capsuleStore *CapsuleStore) UseCapsuleCode(capsuleId uint) error {
+	res, err := capsuleStore.db.Exec(useCapsuleCodeQuery, capsuleId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCapsuleCodeExhausted
+	}
+	return nil
+}
+
+func (capsuleStore *CapsuleStore) RegenerateCapsuleCode(capsuleId uint) (string, error) {
+	code, err := capsuleStore.generateUniqueCapsuleCode()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = capsuleStore.db.Exec("UPDATE capsules SET code = ?, codeUses = 0 WHERE id = ?", code, capsuleId)
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (capsuleStore *CapsuleStore) UpdateCapsuleCodeSettings(capsuleId uint, expiresAt *time.Time, maxUses *uint, revoked bool) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET codeExpiresAt = ?, codeMaxUses = ?, codeRevoked = ? WHERE id = ?", expiresAt, maxUses, revoked, capsuleId)
+	return err
+}
+
 func (capsuleStore *CapsuleStore) JoinCapsule(userId uint, code string) error {
 	// get the capsule
 	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE code = ?", code)
@@ -231,28 +399,106 @@ func (capsuleStore *CapsuleStore) JoinCapsule(userId uint, code string) error {
 	if capsule.Code != code {
 		return fmt.Errorf("capsule not found")
 	}
+	if !capsule.Public {
+		return fmt.Errorf("capsule is private, the owner must approve your request to join")
+	}
+	if err := checkCapsuleCode(*capsule, capsuleStore.clock.Now()); err != nil {
+		return err
+	}
+
+	return capsuleStore.addCapsuleMember(capsule, userId, true)
+}
+
+func (capsuleStore *CapsuleStore) AddCapsuleMember(capsuleId uint, userId uint) error {
+	rows, err := capsuleStore.db.Query("SELECT * FROM capsules WHERE id = ?", capsuleId)
+	if err != nil {
+		return err
+	}
+
+	capsule := new(types.Capsule)
+	for rows.Next() {
+		capsule, err = scanRowIntoCapsule(rows)
+		if err != nil {
+			return err
+		}
+	}
+	if capsule.ID != capsuleId {
+		return fmt.Errorf("capsule not found")
+	}
+
+	return capsuleStore.addCapsuleMember(capsule, userId, false)
+}
+
+// addCapsuleMember adds the user as a contributor, using up one of the code's uses in the same transaction when
+// they joined by code so that someone who loses the race for the last use isn't left in the capsule
+func (capsuleStore *CapsuleStore) addCapsuleMember(capsule *types.Capsule, userId uint, useCode bool) error {
+	var err error
+	capsule.Members, err = capsuleStore.getCapsuleMembers(capsule.ID)
+	if err != nil {
+		return err
+	}
 
 	// check if the user is already a member of the capsule
-	if capsule.CapsuleOwnerID == userId || capsule.CapsuleMember1ID == userId || capsule.CapsuleMember2ID == userId || capsule.CapsuleMember3ID == userId || capsule.CapsuleMember4ID == userId || capsule.CapsuleMember5ID == userId {
+	if findCapsuleMember(*capsule, userId) != nil {
 		return fmt.Errorf("you are already a member of the capsule")
 	}
 
-	// check for the first available member slot
-	if capsule.CapsuleMember1ID == 0 {
-		_, err = capsuleStore.db.Exec("UPDATE capsules SET capsuleMember1Id = ? WHERE id = ?", userId, capsule.ID)
-	} else if capsule.CapsuleMember2ID == 0 {
-		_, err = capsuleStore.db.Exec("UPDATE capsules SET capsuleMember2Id = ? WHERE id = ?", userId, capsule.ID)
-	} else if capsule.CapsuleMember3ID == 0 {
-		_, err = capsuleStore.db.Exec("UPDATE capsules SET capsuleMember3Id = ? WHERE id = ?", userId, capsule.ID)
-	} else if capsule.CapsuleMember4ID == 0 {
-		_, err = capsuleStore.db.Exec("UPDATE capsules SET capsuleMember4Id = ? WHERE id = ?", userId, capsule.ID)
-	} else if capsule.CapsuleMember5ID == 0 {
-		_, err = capsuleStore.db.Exec("UPDATE capsules SET capsuleMember5Id = ? WHERE id = ?", userId, capsule.ID)
-	} else {
+	if uint(len(capsule.Members)) >= capsule.MemberLimit {
 		return fmt.Errorf("capsule already has the maximum number of members")
 	}
 
-	return err
+	tx, err := capsuleStore.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if useCode {
+		res, err := tx.Exec(useCapsuleCodeQuery, capsule.ID)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrCapsuleCodeExhausted
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO capsuleMembers (capsuleId, userId, role) VALUES (?, ?, 'contributor')", capsule.ID, userId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	err = capsuleStore.addTemplatePrompts(capsule.ID, userId)
+	if err != nil {
+		log.Printf("error adding template prompts for user %d in capsule %d: %v", userId, capsule.ID, err)
+	}
+
+	capsuleStore.notifyMemberJoined(capsule, userId)
+	return nil
+}
+
+// notifyMemberJoined lets the owner know, failing to do so shouldn't undo the join
+func (capsuleStore *CapsuleStore) notifyMemberJoined(capsule *types.Capsule, userId uint) {
+	var memberName string
+	err := capsuleStore.db.QueryRow("SELECT name FROM users WHERE id = ?", userId).Scan(&memberName)
+	if err != nil {
+		log.Printf("error fetching user %d for capsule %d: %v", userId, capsule.ID, err)
+		return
+	}
+
+	err = capsuleStore.outboxStore.EnqueuePush(capsule.CapsuleOwnerID, push.MemberJoined(capsule.ID, capsule.Name, memberName), "")
+	if err != nil {
+		log.Printf("error notifying owner of capsule %d: %v", capsule.ID, err)
+	}
 }
 
 func (capsuleStore *CapsuleStore) DeleteCapsule(userId uint, capsuleId uint) ([]string, error) {
@@ -273,6 +519,11 @@ func (capsuleStore *CapsuleStore) DeleteCapsule(userId uint, capsuleId uint) ([]
 		return objectNames, err
 	}
 
+	_, err = capsuleStore.db.Exec("DELETE FROM letters WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
 	// get all photo objectNames
 	rows, err := capsuleStore.db.Query("SELECT objectName FROM photos WHERE capsuleId = ?", capsuleId)
 	if err != nil {
@@ -366,102 +617,210 @@ func (capsuleStore *CapsuleStore) DeleteCapsule(userId uint, capsuleId uint) ([]
 		return objectNames, err
 	}
 
+	_, err = capsuleStore.db.Exec("DELETE o FROM outbox o JOIN capsuleDeliveries d ON o.deliveryId = d.id WHERE d.capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
+	_, err = capsuleStore.db.Exec("DELETE FROM capsuleDeliveries WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
+	_, err = capsuleStore.db.Exec("DELETE FROM joinRequests WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
+	_, err = capsuleStore.db.Exec("DELETE FROM invites WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
+	_, err = capsuleStore.db.Exec("DELETE FROM giftRecipients WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
+	_, err = capsuleStore.db.Exec("DELETE FROM capsuleMembers WHERE capsuleId = ?", capsuleId)
+	if err != nil {
+		return objectNames, err
+	}
+
 	_, err = capsuleStore.db.Exec("DELETE FROM capsules WHERE id = ? AND capsuleOwnerId = ?", capsuleId, userId)
 	return objectNames, err
 }
 
-func (capsuleStore *CapsuleStore) NameCapsule(userId uint, capsuleId uint, name string) error {
-	_, err := capsuleStore.db.Exec("UPDATE capsules SET name = ? WHERE id = ? AND capsuleOwnerId = ?", name, capsuleId, userId)
-	return err
-}
-
-func (capsuleStore *CapsuleStore) SealCapsule(userId uint, capsuleId uint, dateToOpen time.Time) error {
-	_, err := capsuleStore.db.Exec("UPDATE capsules SET sealed = 'sealed', dateToOpen = ? WHERE id = ? AND capsuleOwnerId = ?", dateToOpen, capsuleId, userId)
+func (capsuleStore *CapsuleStore) NameCapsule(capsuleId uint, name string) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET name = ? WHERE id = ?", name, capsuleId)
 	return err
 }
 
-func (capsuleStore *CapsuleStore) MemberSealCapsule(userId uint, capsuleId uint, memberNumber uint) error {
-	if memberNumber < 1 || memberNumber > 5 {
-		return fmt.Errorf("invalid member number")
+// SealCapsule seals the capsule and marks the member who sealed it, any deadline is cleared since it no longer applies
+func (capsuleStore *CapsuleStore) SealCapsule(userId uint, capsuleId uint, dateToOpen time.Time, timezone string) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET sealed = 'sealed', dateToOpen = ?, timezone = ?, contributionDeadline = NULL WHERE id = ?", dateToOpen, timezone, capsuleId)
+	if err != nil {
+		return err
 	}
 
-	query := fmt.Sprintf("UPDATE capsules SET capsuleMember%dSealed = TRUE WHERE id = ? AND capsuleMember%dId = ?", memberNumber, memberNumber)
-	_, err := capsuleStore.db.Exec(query, capsuleId, userId)
+	_, err = capsuleStore.db.Exec("UPDATE capsuleMembers SET sealedAt = ? WHERE capsuleId = ? AND userId = ? AND sealedAt IS NULL", capsuleStore.clock.Now(), capsuleId, userId)
 	return err
 }
 
-func (capsuleStore *CapsuleStore) OpenCapsule(userId uint, capsuleId uint) error {
-	_, err := capsuleStore.db.Exec("UPDATE capsules SET sealed = 'opened' WHERE id = ? AND capsuleOwnerId = ?", capsuleId, userId)
+func (capsuleStore *CapsuleStore) MemberSealCapsule(userId uint, capsuleId uint) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsuleMembers SET sealedAt = ? WHERE capsuleId = ? AND userId = ? AND role != 'owner'", capsuleStore.clock.Now(), capsuleId, userId)
 	return err
 }
 
-func (capsuleStore *CapsuleStore) SendReminderMail() error {
-	findMailingListQuery := `
-		SELECT c.id, u.email
-		FROM capsules c
-		JOIN users u ON c.capsuleOwnerId = u.id
-		WHERE c.sealed = 'sealed' AND c.dateToOpen < NOW() AND c.emailSent = FALSE
-		LIMIT 490
-	`
+func (capsuleStore *CapsuleStore) SetCapsuleMemberRole(capsuleId uint, userId uint, role string) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsuleMembers SET role = ? WHERE capsuleId = ? AND userId = ? AND role != 'owner'", role, capsuleId, userId)
+	return err
+}
 
-	rows, err := capsuleStore.db.Query(findMailingListQuery)
+// RemoveCapsuleMember removes the user from the capsule along with everything they contributed to it,
+// returning the object names of their uploaded files so they can be deleted from storage
+func (capsuleStore *CapsuleStore) RemoveCapsuleMember(capsuleId uint, userId uint) ([]string, error) {
+	tx, err := capsuleStore.db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	emails := make([]string, 0)
-	uniqueEmails := make(map[string]bool)
-	capsuleIds := make([]uint, 0)
-	for rows.Next() {
-		var email string
-		var capsuleId uint
-		if err := rows.Scan(&capsuleId, &email); err != nil {
-			return err
+	var objectNames []string
+	for _, table := range []string{"photos", "audios", "doodles", "miscFiles"} {
+		rows, err := tx.Query("SELECT objectName FROM "+table+" WHERE capsuleId = ? AND userId = ?", capsuleId, userId)
+		if err != nil {
+			return nil, err
 		}
-		if !uniqueEmails[email] {
-			emails = append(emails, email)
-			uniqueEmails[email] = true
+
+		for rows.Next() {
+			var objectName string
+			if err := rows.Scan(&objectName); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			objectNames = append(objectNames, objectName)
 		}
-		capsuleIds = append(capsuleIds, capsuleId)
-	}
-
-	if len(emails) > 0 {
-		auth := smtp.PlainAuth(
-			"",
-			"retrospect.space@gmail.com",
-			config.Envs.GmailAppPassword,
-			"smtp.gmail.com",
-		)
-
-		msg := "Subject: Your Time Capsule is Ready!\n\nYour time capsule is ready to be opened! Open our app to see what's inside!"
-
-		err = smtp.SendMail(
-			"smtp.gmail.com:587",
-			auth,
-			"retrospect.space@gmail.com",
-			emails,
-			[]byte(msg),
-		)
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, table := range []string{"songs", "questionAnswers", "writings", "letters", "photos", "audios", "doodles", "miscFiles"} {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE capsuleId = ? AND userId = ?", capsuleId, userId)
 		if err != nil {
-			return err
+			return nil, err
 		}
+	}
+
+	// letters to the member can't be read by anyone else
+	_, err = tx.Exec("DELETE FROM letters WHERE capsuleId = ? AND recipientId = ?", capsuleId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM capsuleMembers WHERE capsuleId = ? AND userId = ? AND role != 'owner'", capsuleId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the files are only gone from storage once the rows pointing at them are
+	return objectNames, tx.Commit()
+}
+
+// RemoveUserFromCapsules takes a user who is deleting their account out of every capsule they're in. capsules they own
+// go to their longest standing member, or are deleted if nobody else is in them. returns the object names of the
+// files that were removed so they can be deleted from storage
+func (capsuleStore *CapsuleStore) RemoveUserFromCapsules(userId uint) ([]string, error) {
+	capsules, err := capsuleStore.GetCapsules(userId)
+	if err != nil {
+		return nil, err
+	}
 
-		capsuleStringIds := make([]string, len(capsuleIds))
-		for i, capsuleId := range capsuleIds {
-			capsuleStringIds[i] = fmt.Sprint(capsuleId)
+	var objectNames []string
+	for _, capsule := range capsules {
+		if capsule.CapsuleOwnerID == userId {
+			var successorId uint
+			err = capsuleStore.db.QueryRow(
+				"SELECT userId FROM capsuleMembers WHERE capsuleId = ? AND userId != ? ORDER BY joinedAt, id LIMIT 1",
+				capsule.ID, userId,
+			).Scan(&successorId)
+			if err == sql.ErrNoRows {
+				deleted, err := capsuleStore.DeleteCapsule(userId, capsule.ID)
+				if err != nil {
+					return objectNames, fmt.Errorf("error deleting capsule %d: %w", capsule.ID, err)
+				}
+				objectNames = append(objectNames, deleted...)
+				continue
+			}
+			if err != nil {
+				return objectNames, err
+			}
+
+			err = capsuleStore.TransferCapsuleOwnership(capsule.ID, userId, successorId)
+			if err != nil {
+				return objectNames, fmt.Errorf("error transferring capsule %d: %w", capsule.ID, err)
+			}
 		}
-		// update db to mark capsules as emailSent
-		updateEmailSentQuery := `
-			UPDATE capsules
-			SET emailSent = TRUE
-			WHERE id IN (` + strings.Join(capsuleStringIds, ",") + `)
-		`
-		_, err = capsuleStore.db.Exec(updateEmailSentQuery)
+
+		removed, err := capsuleStore.RemoveCapsuleMember(capsule.ID, userId)
 		if err != nil {
-			return err
+			return objectNames, fmt.Errorf("error leaving capsule %d: %w", capsule.ID, err)
 		}
+		objectNames = append(objectNames, removed...)
 	}
 
-	return nil
+	return objectNames, nil
+}
+
+// TransferCapsuleOwnership hands the capsule to another member, the previous owner stays on as an editor
+func (capsuleStore *CapsuleStore) TransferCapsuleOwnership(capsuleId uint, ownerId uint, newOwnerId uint) error {
+	tx, err := capsuleStore.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE capsules SET capsuleOwnerId = ? WHERE id = ? AND capsuleOwnerId = ?", newOwnerId, capsuleId, ownerId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("you are not the owner of the capsule")
+	}
+
+	_, err = tx.Exec("UPDATE capsuleMembers SET role = 'editor' WHERE capsuleId = ? AND userId = ?", capsuleId, ownerId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE capsuleMembers SET role = 'owner' WHERE capsuleId = ? AND userId = ?", capsuleId, newOwnerId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (capsuleStore *CapsuleStore) OpenCapsule(capsuleId uint) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET sealed = 'opened', openedAt = COALESCE(openedAt, ?) WHERE id = ?", capsuleStore.clock.Now(), capsuleId)
+	return err
+}
+
+// OpenDueCapsules opens every sealed capsule whose date to open has passed, except quorum capsules which
+// only open once enough members have voted
+func (capsuleStore *CapsuleStore) OpenDueCapsules() (int64, error) {
+	now := capsuleStore.clock.Now()
+	res, err := capsuleStore.db.Exec(
+		"UPDATE capsules SET sealed = 'opened', openedAt = ? WHERE sealed = 'sealed' AND dateToOpen < ? AND openPolicy != ?",
+		now, now, types.CapsuleOpenPolicyQuorum,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
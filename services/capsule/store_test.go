@@ -0,0 +1,56 @@
+package capsule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/services/clock"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+func TestCheckCapsuleCodeExpiry(t *testing.T) {
+	fakeClock := &clock.FakeClock{}
+	expiresAt := fakeClock.Now().Add(7 * 24 * time.Hour)
+	capsule := types.Capsule{CodeExpiresAt: &expiresAt}
+
+	if err := checkCapsuleCode(capsule, fakeClock.Now()); err != nil {
+		t.Fatalf("a code that expires next week was refused: %v", err)
+	}
+
+	fakeClock.Advance(7*24*time.Hour - time.Minute)
+	if err := checkCapsuleCode(capsule, fakeClock.Now()); err != nil {
+		t.Errorf("a code with a minute left was refused: %v", err)
+	}
+
+	fakeClock.Advance(2 * time.Minute)
+	if err := checkCapsuleCode(capsule, fakeClock.Now()); err != ErrCapsuleCodeExpired {
+		t.Errorf("an expired code got %v, want %v", err, ErrCapsuleCodeExpired)
+	}
+
+	// resetting the clock brings the code back, nothing about the capsule was changed
+	fakeClock.Reset()
+	if err := checkCapsuleCode(capsule, fakeClock.Now()); err != nil {
+		t.Errorf("after resetting the clock the code was refused: %v", err)
+	}
+}
+
+func TestCheckCapsuleCodeLimits(t *testing.T) {
+	now := (&clock.FakeClock{}).Now()
+	maxUses := uint(3)
+
+	tests := []struct {
+		name    string
+		capsule types.Capsule
+		want    error
+	}{
+		{"open code", types.Capsule{}, nil},
+		{"uses left", types.Capsule{CodeMaxUses: &maxUses, CodeUses: 2}, nil},
+		{"used up", types.Capsule{CodeMaxUses: &maxUses, CodeUses: 3}, ErrCapsuleCodeExhausted},
+		{"revoked", types.Capsule{CodeRevoked: true}, ErrCapsuleCodeRevoked},
+	}
+	for _, test := range tests {
+		if err := checkCapsuleCode(test.capsule, now); err != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package capsule
+
+import (
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+func (capsuleStore *CapsuleStore) SetCapsuleSurprise(capsuleId uint, surprise bool) error {
+	_, err := capsuleStore.db.Exec("UPDATE capsules SET surprise = ? WHERE id = ?", surprise, capsuleId)
+	return err
+}
+
+// GetHiddenContributionCounts counts what everyone but the user has added, so a surprise capsule can
+// show how full it is without giving away what's inside
+func (capsuleStore *CapsuleStore) GetHiddenContributionCounts(capsuleId uint, userId uint) (types.ContributionCounts, error) {
+	var counts types.ContributionCounts
+
+	countContributionsQuery := `
+		SELECT
+			(SELECT COUNT(*) FROM songs WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM questionAnswers WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM writings WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM photos WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM audios WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM doodles WHERE capsuleId = ? AND userId != ?),
+			(SELECT COUNT(*) FROM miscFiles WHERE capsuleId = ? AND userId != ?)
+	`
+	args := make([]any, 0, 14)
+	for i := 0; i < 7; i++ {
+		args = append(args, capsuleId, userId)
+	}
+
+	err := capsuleStore.db.QueryRow(countContributionsQuery, args...).Scan(
+		&counts.Songs,
+		&counts.QuestionAnswers,
+		&counts.Writings,
+		&counts.Photos,
+		&counts.Audios,
+		&counts.Doodles,
+		&counts.MiscFiles,
+	)
+	return counts, err
+}
@@ -0,0 +1,54 @@
+package capsule
+
+import (
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+)
+
+// CreateCapsuleFromTemplate creates a capsule with the template's vessel and name, opening the template's
+// number of days from today in the owner's timezone, and gives the owner an empty answer to each prompt
+func (capsuleStore *CapsuleStore) CreateCapsuleFromTemplate(userId uint, template types.CapsuleTemplate, public bool, memberLimit uint, surprise bool) (uint, error) {
+	capsuleId, err := capsuleStore.CreateCapsule(userId, template.Vessel, public, memberLimit, surprise)
+	if err != nil {
+		return 0, err
+	}
+
+	var dateToOpen *time.Time
+	if template.OpenAfterDays != nil {
+		var timezone string
+		err = capsuleStore.db.QueryRow("SELECT timezone FROM capsules WHERE id = ?", capsuleId).Scan(&timezone)
+		if err != nil {
+			return capsuleId, err
+		}
+		loc := utils.LoadLocation(timezone)
+		today := capsuleStore.clock.Now().In(loc)
+		date := time.Date(today.Year(), today.Month(), today.Day()+int(*template.OpenAfterDays), 0, 0, 0, 0, loc).UTC()
+		dateToOpen = &date
+	}
+
+	_, err = capsuleStore.db.Exec(
+		"UPDATE capsules SET name = ?, dateToOpen = ?, templateId = ? WHERE id = ?",
+		template.CapsuleName, dateToOpen, template.ID, capsuleId,
+	)
+	if err != nil {
+		return capsuleId, err
+	}
+
+	return capsuleId, capsuleStore.addTemplatePrompts(capsuleId, userId)
+}
+
+// addTemplatePrompts gives the member an empty answer to each prompt of the template the capsule was created from
+func (capsuleStore *CapsuleStore) addTemplatePrompts(capsuleId uint, userId uint) error {
+	addPromptsQuery := `
+		INSERT INTO questionAnswers (userId, capsuleId, prompt, answer)
+		SELECT ?, c.id, p.prompt, ''
+		FROM capsules c
+		JOIN capsuleTemplatePrompts p ON p.templateId = c.templateId
+		WHERE c.id = ?
+		ORDER BY p.position
+	`
+	_, err := capsuleStore.db.Exec(addPromptsQuery, userId, capsuleId)
+	return err
+}
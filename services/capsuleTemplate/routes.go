@@ -0,0 +1,93 @@
+package capsuleTemplate
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	templateStore types.CapsuleTemplateStore
+	userStore     types.UserStore
+}
+
+func NewHandler(templateStore types.CapsuleTemplateStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		templateStore: templateStore,
+		userStore:     userStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/templates", auth.WithJWTAuth(handler.handleGetCapsuleTemplates, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/templates/create", auth.WithJWTAuth(handler.handleCreateCapsuleTemplate, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/templates/delete", auth.WithJWTAuth(handler.handleDeleteCapsuleTemplate, handler.userStore)).Methods(http.MethodPost)
+}
+
+func (handler *Handler) handleGetCapsuleTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	templates, err := handler.templateStore.GetCapsuleTemplates(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string][]types.CapsuleTemplate{"templates": templates})
+}
+
+func (handler *Handler) handleCreateCapsuleTemplate(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.CreateCapsuleTemplatePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	templateID, err := handler.templateStore.CreateCapsuleTemplate(userID, payload.Name, payload.Vessel, payload.CapsuleName, payload.OpenAfterDays, payload.Prompts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": templateID})
+}
+
+func (handler *Handler) handleDeleteCapsuleTemplate(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.DeleteCapsuleTemplatePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	err = handler.templateStore.DeleteCapsuleTemplate(userID, payload.TemplateID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,177 @@
+package capsuleTemplate
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type CapsuleTemplateStore struct {
+	db *sql.DB
+}
+
+func NewCapsuleTemplateStore(db *sql.DB) *CapsuleTemplateStore {
+	return &CapsuleTemplateStore{
+		db: db,
+	}
+}
+
+func scanRowIntoCapsuleTemplate(row *sql.Rows) (*types.CapsuleTemplate, error) {
+	template := new(types.CapsuleTemplate)
+
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.Vessel,
+		&template.CapsuleName,
+		&template.OpenAfterDays,
+		&template.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// getPrompts fills in the prompts of each template in order
+func (templateStore *CapsuleTemplateStore) getPrompts(templates []types.CapsuleTemplate) error {
+	for i := range templates {
+		rows, err := templateStore.db.Query("SELECT prompt FROM capsuleTemplatePrompts WHERE templateId = ? ORDER BY position", templates[i].ID)
+		if err != nil {
+			return err
+		}
+
+		templates[i].Prompts = make([]string, 0)
+		for rows.Next() {
+			var prompt string
+			if err := rows.Scan(&prompt); err != nil {
+				rows.Close()
+				return err
+			}
+			templates[i].Prompts = append(templates[i].Prompts, prompt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCapsuleTemplates lists the templates we provide followed by the ones the user saved
+func (templateStore *CapsuleTemplateStore) GetCapsuleTemplates(userId uint) ([]types.CapsuleTemplate, error) {
+	rows, err := templateStore.db.Query("SELECT * FROM capsuleTemplates WHERE userId IS NULL OR userId = ? ORDER BY userId IS NOT NULL, id", userId)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]types.CapsuleTemplate, 0)
+	for rows.Next() {
+		template, err := scanRowIntoCapsuleTemplate(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = templateStore.getPrompts(templates)
+	return templates, err
+}
+
+// GetCapsuleTemplateById only finds templates we provide and the ones the user saved
+func (templateStore *CapsuleTemplateStore) GetCapsuleTemplateById(userId uint, templateId uint) (*types.CapsuleTemplate, error) {
+	rows, err := templateStore.db.Query("SELECT * FROM capsuleTemplates WHERE id = ? AND (userId IS NULL OR userId = ?)", templateId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	template := new(types.CapsuleTemplate)
+	for rows.Next() {
+		template, err = scanRowIntoCapsuleTemplate(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+	}
+	rows.Close()
+
+	if template.ID != templateId {
+		return nil, fmt.Errorf("template not found")
+	}
+
+	templates := []types.CapsuleTemplate{*template}
+	err = templateStore.getPrompts(templates)
+	return &templates[0], err
+}
+
+func (templateStore *CapsuleTemplateStore) CreateCapsuleTemplate(userId uint, name string, vessel string, capsuleName string, openAfterDays *uint, prompts []string) (uint, error) {
+	tx, err := templateStore.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO capsuleTemplates (userId, name, vessel, capsuleName, openAfterDays) VALUES (?, ?, ?, ?, ?)",
+		userId, name, vessel, capsuleName, openAfterDays,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, prompt := range prompts {
+		_, err = tx.Exec("INSERT INTO capsuleTemplatePrompts (templateId, prompt, position) VALUES (?, ?, ?)", id, prompt, i+1)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return uint(id), tx.Commit()
+}
+
+// DeleteCapsuleTemplate deletes one of the user's templates, capsules created from it keep the prompts they already have
+func (templateStore *CapsuleTemplateStore) DeleteCapsuleTemplate(userId uint, templateId uint) error {
+	var ownerId *uint
+	err := templateStore.db.QueryRow("SELECT userId FROM capsuleTemplates WHERE id = ?", templateId).Scan(&ownerId)
+	if err == sql.ErrNoRows || (err == nil && (ownerId == nil || *ownerId != userId)) {
+		return fmt.Errorf("template not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := templateStore.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE capsules SET templateId = NULL WHERE templateId = ?", templateId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM capsuleTemplatePrompts WHERE templateId = ?", templateId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM capsuleTemplates WHERE id = ? AND userId = ?", templateId, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// NewClock picks the clock from the environment, the fake clock is never used in production
+func NewClock() types.Clock {
+	switch config.Envs.ClockBackend {
+	case "real":
+		return RealClock{}
+	case "fake":
+		if config.Envs.GoEnv == "production" {
+			log.Fatal("The fake clock can't be used in production")
+		}
+		log.Println("Using a fake clock, capsule times can be moved forward through /dev/clock")
+		return &FakeClock{}
+	default:
+		log.Fatalf("Unknown clock: %s", config.Envs.ClockBackend)
+		return nil
+	}
+}
+
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock keeps ticking in real time from an offset that can be moved forward,
+// so a capsule can be walked from preseal to opened without waiting.
+// the offset only lives in this process, so run a single instance when using it
+type FakeClock struct {
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return time.Now().Add(clock.offset)
+}
+
+func (clock *FakeClock) Offset() time.Duration {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.offset
+}
+
+func (clock *FakeClock) Advance(duration time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.offset += duration
+}
+
+// Reset brings the clock back to real time
+func (clock *FakeClock) Reset() {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.offset = 0
+}
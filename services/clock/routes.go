@@ -0,0 +1,67 @@
+package clock
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	fakeClock *FakeClock // nil unless the clock is fake
+}
+
+func NewHandler(clock types.Clock) *Handler {
+	fakeClock, _ := clock.(*FakeClock)
+	return &Handler{
+		fakeClock: fakeClock,
+	}
+}
+
+// RegisterRoutes only adds the routes when the clock is fake, there's nothing to move otherwise
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	if handler.fakeClock == nil {
+		return
+	}
+
+	router.HandleFunc("/dev/clock", auth.WithAdminAPIKey(handler.handleGetClock)).Methods(http.MethodGet)
+	router.HandleFunc("/dev/clock/advance", auth.WithAdminAPIKey(handler.handleAdvanceClock)).Methods(http.MethodPost)
+	router.HandleFunc("/dev/clock/reset", auth.WithAdminAPIKey(handler.handleResetClock)).Methods(http.MethodPost)
+}
+
+func (handler *Handler) handleGetClock(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, types.ClockResponse{
+		Now:             handler.fakeClock.Now(),
+		OffsetInSeconds: int64(handler.fakeClock.Offset().Seconds()),
+	})
+}
+
+func (handler *Handler) handleAdvanceClock(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.AdvanceClockPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	handler.fakeClock.Advance(time.Second * time.Duration(payload.Seconds))
+	handler.handleGetClock(w, r)
+}
+
+func (handler *Handler) handleResetClock(w http.ResponseWriter, r *http.Request) {
+	handler.fakeClock.Reset()
+	handler.handleGetClock(w, r)
+}
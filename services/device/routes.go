@@ -0,0 +1,96 @@
+package device
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	deviceStore types.DeviceStore
+	userStore   types.UserStore
+}
+
+func NewHandler(deviceStore types.DeviceStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		deviceStore: deviceStore,
+		userStore:   userStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/devices", auth.WithJWTAuth(handler.handleGetDevices, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/devices/register", auth.WithJWTAuth(handler.handleRegisterDevice, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/devices/unregister", auth.WithJWTAuth(handler.handleUnregisterDevice, handler.userStore)).Methods(http.MethodPost)
+}
+
+func (handler *Handler) handleGetDevices(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	devices, err := handler.deviceStore.GetUserDevices(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, devices)
+}
+
+func (handler *Handler) handleRegisterDevice(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RegisterDevicePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	err = handler.deviceStore.RegisterDevice(userID, payload.Platform, payload.Token)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, nil)
+}
+
+func (handler *Handler) handleUnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.UnregisterDevicePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	err = handler.deviceStore.DeleteUserDevice(userID, payload.Token)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,100 @@
+package device
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type DeviceStore struct {
+	db *sql.DB
+}
+
+func NewDeviceStore(db *sql.DB) *DeviceStore {
+	return &DeviceStore{
+		db: db,
+	}
+}
+
+func scanRowIntoDevice(row *sql.Rows) (*types.Device, error) {
+	device := new(types.Device)
+
+	err := row.Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Platform,
+		&device.Token,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (deviceStore *DeviceStore) GetUserDevices(userId uint) ([]types.Device, error) {
+	rows, err := deviceStore.db.Query("SELECT * FROM devices WHERE userId = ? ORDER BY updatedAt DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]types.Device, 0)
+	for rows.Next() {
+		device, err := scanRowIntoDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return devices, nil
+}
+
+func (deviceStore *DeviceStore) GetDeviceById(deviceId uint) (*types.Device, error) {
+	rows, err := deviceStore.db.Query("SELECT * FROM devices WHERE id = ?", deviceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	device := new(types.Device)
+	for rows.Next() {
+		device, err = scanRowIntoDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if device.ID != deviceId {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	return device, nil
+}
+
+// RegisterDevice saves the token for the user, a token that moved to a new account (eg. after logging out and in) follows it
+func (deviceStore *DeviceStore) RegisterDevice(userId uint, platform string, token string) error {
+	_, err := deviceStore.db.Exec(
+		"INSERT INTO devices (userId, platform, token) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE userId = VALUES(userId), platform = VALUES(platform), updatedAt = NOW()",
+		userId, platform, token,
+	)
+	return err
+}
+
+func (deviceStore *DeviceStore) DeleteUserDevice(userId uint, token string) error {
+	_, err := deviceStore.db.Exec("DELETE FROM devices WHERE userId = ? AND token = ?", userId, token)
+	return err
+}
+
+func (deviceStore *DeviceStore) DeleteDevice(deviceId uint) error {
+	_, err := deviceStore.db.Exec("DELETE FROM devices WHERE id = ?", deviceId)
+	return err
+}
@@ -50,10 +50,10 @@ func (handler *Handler) handleCreateDoodle(w http.ResponseWriter, r *http.Reques
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	// check if user is member of capsule
-	_, err = handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("could not find capsule with id %d", payload.CapsuleID))
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
 
@@ -83,6 +83,13 @@ func (handler *Handler) handleDeleteDoodle(w http.ResponseWriter, r *http.Reques
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
 	objectName, err := handler.doodleStore.DeleteDoodle(userID, payload.CapsuleID, payload.DoodleID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
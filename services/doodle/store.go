@@ -35,8 +35,11 @@ func scanRowIntoDoodle(row *sql.Rows) (*types.Doodle, error) {
 	return doodle, nil
 }
 
-func (doodleStore *DoodleStore) GetDoodles(capsuleID uint) ([]types.Doodle, error) {
-	rows, err := doodleStore.db.Query("SELECT * FROM doodles WHERE capsuleId = ?", capsuleID)
+func (doodleStore *DoodleStore) GetDoodles(capsuleID uint, userID uint) ([]types.Doodle, error) {
+	rows, err := doodleStore.db.Query(
+		"SELECT d.* FROM doodles d JOIN capsules c ON d.capsuleId = c.id WHERE d.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR d.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+package gift
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	giftStore    types.GiftStore
+	capsuleStore types.CapsuleStore
+	userStore    types.UserStore
+}
+
+func NewHandler(giftStore types.GiftStore, capsuleStore types.CapsuleStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		giftStore:    giftStore,
+		capsuleStore: capsuleStore,
+		userStore:    userStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/gifts/recipients/{capsuleId}", auth.WithJWTAuth(handler.handleGetGiftRecipients, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/gifts/recipients/add", auth.WithJWTAuth(handler.handleAddGiftRecipient, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/gifts/recipients/remove", auth.WithJWTAuth(handler.handleRemoveGiftRecipient, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/gifts/claim", auth.WithJWTAuth(handler.handleClaimGift, handler.userStore)).Methods(http.MethodPost)
+}
+
+// ClaimGiftToken adds the user to the capsule the gift link was sent for
+func ClaimGiftToken(giftStore types.GiftStore, token string, userId uint) error {
+	recipientId, err := auth.VerifyGiftToken(token)
+	if err != nil {
+		return err
+	}
+
+	return giftStore.ClaimGift(recipientId, userId)
+}
+
+func (handler *Handler) handleGetGiftRecipients(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsuleId not provided"))
+		return
+	}
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid capsuleId"))
+		return
+	}
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionView)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	recipients, err := handler.giftStore.GetCapsuleGiftRecipients(uint(capsuleId))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for i := range recipients {
+		recipients[i].Link = mail.GiftLink(recipients[i].ID)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string][]types.GiftRecipient{"recipients": recipients})
+}
+
+func (handler *Handler) handleAddGiftRecipient(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.AddGiftRecipientPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	// recipients who already have an account also get a push when the capsule opens
+	var recipientUserID *uint
+	user, err := handler.userStore.GetUserByEmail(payload.Email)
+	if err != nil && payload.Phone != "" {
+		user, err = handler.userStore.GetUserByPhone(payload.Phone)
+	}
+	if err == nil {
+		for _, member := range capsule.Members {
+			if member.UserID == user.ID {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("recipient is already a member of the capsule"))
+				return
+			}
+		}
+		recipientUserID = &user.ID
+	}
+
+	recipientID, err := handler.giftStore.CreateGiftRecipient(payload.CapsuleID, userID, payload.Name, payload.Email, payload.Phone, recipientUserID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": recipientID})
+}
+
+func (handler *Handler) handleRemoveGiftRecipient(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RemoveGiftRecipientPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	err = handler.giftStore.DeleteGiftRecipient(payload.CapsuleID, payload.RecipientID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleClaimGift(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.GiftTokenPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	err = ClaimGiftToken(handler.giftStore, payload.Token, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,249 @@
+package gift
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type GiftStore struct {
+	db          *sql.DB
+	outboxStore types.OutboxStore
+	clock       types.Clock
+}
+
+func NewGiftStore(db *sql.DB, outboxStore types.OutboxStore, clock types.Clock) *GiftStore {
+	return &GiftStore{
+		db:          db,
+		outboxStore: outboxStore,
+		clock:       clock,
+	}
+}
+
+func scanRowIntoGiftRecipient(row *sql.Rows) (*types.GiftRecipient, error) {
+	recipient := new(types.GiftRecipient)
+
+	err := row.Scan(
+		&recipient.ID,
+		&recipient.CapsuleID,
+		&recipient.SenderID,
+		&recipient.Name,
+		&recipient.Email,
+		&recipient.Phone,
+		&recipient.UserID,
+		&recipient.DeliveredAt,
+		&recipient.ClaimedAt,
+		&recipient.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return recipient, nil
+}
+
+func (giftStore *GiftStore) GetGiftRecipientById(recipientId uint) (*types.GiftRecipient, error) {
+	rows, err := giftStore.db.Query("SELECT * FROM giftRecipients WHERE id = ?", recipientId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipient := new(types.GiftRecipient)
+	for rows.Next() {
+		recipient, err = scanRowIntoGiftRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if recipient.ID != recipientId {
+		return nil, fmt.Errorf("gift not found")
+	}
+
+	return recipient, nil
+}
+
+func (giftStore *GiftStore) GetCapsuleGiftRecipients(capsuleId uint) ([]types.GiftRecipient, error) {
+	rows, err := giftStore.db.Query("SELECT * FROM giftRecipients WHERE capsuleId = ? ORDER BY id", capsuleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := make([]types.GiftRecipient, 0)
+	for rows.Next() {
+		recipient, err := scanRowIntoGiftRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, *recipient)
+	}
+
+	return recipients, nil
+}
+
+func (giftStore *GiftStore) CreateGiftRecipient(capsuleId uint, senderId uint, name string, email string, phone string, userId *uint) (uint, error) {
+	res, err := giftStore.db.Exec(
+		"INSERT INTO giftRecipients (capsuleId, senderId, name, email, phone, userId) VALUES (?, ?, ?, ?, ?, ?)",
+		capsuleId, senderId, name, email, phone, userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
+
+func (giftStore *GiftStore) DeleteGiftRecipient(capsuleId uint, recipientId uint) error {
+	_, err := giftStore.db.Exec("DELETE FROM giftRecipients WHERE id = ? AND capsuleId = ?", recipientId, capsuleId)
+	return err
+}
+
+// ClaimGift adds the user to the capsule as a viewer. the recipient is who the capsule was made for,
+// so the member limit doesn't apply, and anyone who is already a member keeps their role
+func (giftStore *GiftStore) ClaimGift(recipientId uint, userId uint) error {
+	recipient, err := giftStore.GetGiftRecipientById(recipientId)
+	if err != nil {
+		return err
+	}
+	if recipient.ClaimedAt != nil {
+		return fmt.Errorf("gift has already been claimed")
+	}
+
+	tx, err := giftStore.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE giftRecipients SET userId = ?, claimedAt = ? WHERE id = ? AND claimedAt IS NULL",
+		userId, giftStore.clock.Now(), recipientId,
+	)
+	if err != nil {
+		return err
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return fmt.Errorf("gift has already been claimed")
+	}
+
+	_, err = tx.Exec("INSERT IGNORE INTO capsuleMembers (capsuleId, userId, role) VALUES (?, ?, 'viewer')", recipient.CapsuleID, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// undeliveredGift is a recipient of an opened capsule along with what goes in their mail
+type undeliveredGift struct {
+	recipientId   uint
+	capsuleId     uint
+	recipientName string
+	email         string
+	userId        *uint
+	capsuleName   string
+	vessel        string
+	dateToOpen    *time.Time
+	timezone      string
+	senderName    string
+}
+
+// DeliverGifts sends every recipient of an opened capsule their link, recipients with only a phone number
+// are marked as delivered so the members can share the link with them
+func (giftStore *GiftStore) DeliverGifts() (int64, error) {
+	findUndeliveredQuery := `
+		SELECT g.id, g.capsuleId, g.name, g.email, g.userId, c.name, c.vessel, c.dateToOpen, c.timezone, u.name
+		FROM giftRecipients g
+		JOIN capsules c ON g.capsuleId = c.id
+		JOIN users u ON g.senderId = u.id
+		WHERE g.deliveredAt IS NULL AND c.sealed = 'opened'
+		ORDER BY g.id
+	`
+	rows, err := giftStore.db.Query(findUndeliveredQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	gifts := make([]undeliveredGift, 0)
+	for rows.Next() {
+		var g undeliveredGift
+		err := rows.Scan(&g.recipientId, &g.capsuleId, &g.recipientName, &g.email, &g.userId, &g.capsuleName, &g.vessel, &g.dateToOpen, &g.timezone, &g.senderName)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		gifts = append(gifts, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var delivered int64
+	for _, g := range gifts {
+		// claiming the delivery first keeps a recipient from getting the link twice
+		res, err := giftStore.db.Exec("UPDATE giftRecipients SET deliveredAt = ? WHERE id = ? AND deliveredAt IS NULL", giftStore.clock.Now(), g.recipientId)
+		if err != nil {
+			return delivered, err
+		}
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return delivered, err
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		err = giftStore.enqueueGift(g)
+		if err != nil {
+			// give the claim back so the next run tries again, the jobs are keyed by the recipient so whatever
+			// was already queued isn't queued twice
+			_, releaseErr := giftStore.db.Exec("UPDATE giftRecipients SET deliveredAt = NULL WHERE id = ?", g.recipientId)
+			if releaseErr != nil {
+				return delivered, fmt.Errorf("error delivering gift %d: %v, and releasing it: %w", g.recipientId, err, releaseErr)
+			}
+			return delivered, fmt.Errorf("error delivering gift %d: %w", g.recipientId, err)
+		}
+
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+func (giftStore *GiftStore) enqueueGift(g undeliveredGift) error {
+	if g.email != "" {
+		data := mail.CapsuleTemplateData(g.capsuleId, g.capsuleName, g.vessel, g.dateToOpen, g.timezone)
+		data.RecipientName = g.recipientName
+		data.ActorName = g.senderName
+		data.Link = mail.GiftLink(g.recipientId)
+		giftMail, err := mail.RenderMail([]string{g.email}, mail.TemplateGiftReady, data)
+		if err != nil {
+			return err
+		}
+		_, err = giftStore.outboxStore.EnqueueMail(giftMail, nil, fmt.Sprintf("gift-mail-%d", g.recipientId))
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.userId != nil {
+		return giftStore.outboxStore.EnqueuePush(*g.userId, push.GiftReady(g.capsuleId, g.capsuleName, g.senderName), fmt.Sprintf("gift-push-%d", g.recipientId))
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package invite
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	inviteStore     types.InviteStore
+	capsuleStore    types.CapsuleStore
+	userStore       types.UserStore
+	mailer          types.Mailer
+	pusher          types.Pusher
+	preferenceStore types.NotificationPreferenceStore
+}
+
+func NewHandler(
+	inviteStore types.InviteStore,
+	capsuleStore types.CapsuleStore,
+	userStore types.UserStore,
+	mailer types.Mailer,
+	pusher types.Pusher,
+	preferenceStore types.NotificationPreferenceStore,
+) *Handler {
+	return &Handler{
+		inviteStore:     inviteStore,
+		capsuleStore:    capsuleStore,
+		userStore:       userStore,
+		mailer:          mailer,
+		pusher:          pusher,
+		preferenceStore: preferenceStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/invites", auth.WithJWTAuth(handler.handleGetInvites, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/capsule/{capsuleId}", auth.WithJWTAuth(handler.handleGetCapsuleInvites, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/invites/create", auth.WithJWTAuth(handler.handleCreateInvite, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/invites/accept", auth.WithJWTAuth(handler.handleAcceptInvite, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/invites/decline", auth.WithJWTAuth(handler.handleDeclineInvite, handler.userStore)).Methods(http.MethodPost)
+}
+
+// ClaimPendingInvites adds a newly registered user to every capsule they were invited to before signing up
+func ClaimPendingInvites(inviteStore types.InviteStore, capsuleStore types.CapsuleStore, user *types.User) {
+	invites, err := inviteStore.GetPendingInvites(user.Email, utils.NormalizePhone(user.Phone))
+	if err != nil {
+		log.Printf("error fetching pending invites for user %d: %v", user.ID, err)
+		return
+	}
+
+	for _, invite := range invites {
+		err = capsuleStore.AddCapsuleMember(invite.CapsuleID, user.ID)
+		if err != nil {
+			log.Printf("error adding user %d to capsule %d from invite %d: %v", user.ID, invite.CapsuleID, invite.ID, err)
+			continue
+		}
+		err = inviteStore.UpdateInviteStatus(invite.ID, "accepted")
+		if err != nil {
+			log.Printf("error accepting invite %d: %v", invite.ID, err)
+		}
+	}
+}
+
+func (handler *Handler) handleGetInvites(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	user, err := handler.userStore.GetUserById(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	invites, err := handler.inviteStore.GetPendingInvites(user.Email, utils.NormalizePhone(user.Phone))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, invites)
+}
+
+func (handler *Handler) handleGetCapsuleInvites(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsuleId not provided"))
+		return
+	}
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid capsuleId"))
+		return
+	}
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	invites, err := handler.inviteStore.GetCapsuleInvites(uint(capsuleId))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, invites)
+}
+
+func (handler *Handler) handleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.CreateInvitePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	// let the app know whether the invitee already has an account, or needs to be sent a sign up link
+	phone := utils.NormalizePhone(payload.Phone)
+	var invitee *types.User
+	if payload.Email != "" {
+		invitee, _ = handler.userStore.GetUserByEmail(payload.Email)
+	}
+	if invitee == nil && phone != "" {
+		invitee, _ = handler.userStore.GetUserByPhone(phone)
+	}
+
+	inviteID, err := handler.inviteStore.CreateInvite(payload.CapsuleID, userID, payload.Email, phone)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	handler.notifyInvitee(capsule, userID, payload.Email, invitee)
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"id": inviteID, "registered": invitee != nil})
+}
+
+// notifyInvitee emails the invite and pushes it to the invitee's devices if they have an account,
+// failing to do so shouldn't undo the invite
+func (handler *Handler) notifyInvitee(capsule types.Capsule, inviterID uint, email string, invitee *types.User) {
+	inviterName, err := handler.userStore.GetUserNameById(inviterID)
+	if err != nil {
+		log.Printf("error fetching user %d for capsule %d invite: %v", inviterID, capsule.ID, err)
+		return
+	}
+
+	if invitee != nil {
+		err = handler.pusher.Push(invitee.ID, push.CapsuleInvite(capsule.ID, capsule.Name, inviterName))
+		if err != nil {
+			log.Printf("error pushing invite for capsule %d: %v", capsule.ID, err)
+		}
+	}
+	if email == "" {
+		return
+	}
+
+	data := mail.CapsuleTemplateData(capsule.ID, capsule.Name, capsule.Vessel, capsule.DateToOpen, capsule.Timezone)
+	// people without an account haven't got preferences to honour
+	if invitee != nil {
+		mailEnabled, err := handler.preferenceStore.IsNotificationEnabled(invitee.ID, types.NotificationChannelEmail, types.NotificationEventCapsuleInvite)
+		if err != nil {
+			log.Printf("error fetching notification preferences of user %d: %v", invitee.ID, err)
+			return
+		}
+		if !mailEnabled {
+			return
+		}
+		data.RecipientName = invitee.Name
+		data.UnsubscribeLink = mail.UnsubscribeLink(invitee.ID, types.NotificationEventCapsuleInvite)
+	}
+	data.ActorName = inviterName
+	data.Link = config.Envs.PublicHost + "/invites"
+	inviteMail, err := mail.RenderMail([]string{email}, mail.TemplateCapsuleInvite, data)
+	if err != nil {
+		log.Printf("error rendering invite mail for capsule %d: %v", capsule.ID, err)
+		return
+	}
+	if err := handler.mailer.Send(inviteMail); err != nil {
+		log.Printf("error mailing invite for capsule %d: %v", capsule.ID, err)
+	}
+}
+
+// getOwnPendingInvite fetches an invite and checks that it is pending and addressed to the user
+func (handler *Handler) getOwnPendingInvite(userID uint, inviteID uint) (*types.Invite, int, error) {
+	user, err := handler.userStore.GetUserById(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	invite, err := handler.inviteStore.GetInviteById(inviteID)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if (invite.Email == "" || invite.Email != user.Email) && (invite.Phone == "" || invite.Phone != utils.NormalizePhone(user.Phone)) {
+		return nil, http.StatusForbidden, fmt.Errorf("this invite was not sent to you")
+	}
+	if invite.Status != "pending" {
+		return nil, http.StatusBadRequest, fmt.Errorf("invite is %s", invite.Status)
+	}
+
+	return invite, http.StatusOK, nil
+}
+
+func (handler *Handler) handleAcceptInvite(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RespondInvitePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	invite, status, err := handler.getOwnPendingInvite(userID, payload.InviteID)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	err = handler.capsuleStore.AddCapsuleMember(invite.CapsuleID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = handler.inviteStore.UpdateInviteStatus(invite.ID, "accepted")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"capsuleId": invite.CapsuleID})
+}
+
+func (handler *Handler) handleDeclineInvite(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RespondInvitePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	invite, status, err := handler.getOwnPendingInvite(userID, payload.InviteID)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	err = handler.inviteStore.UpdateInviteStatus(invite.ID, "declined")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
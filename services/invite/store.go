@@ -0,0 +1,180 @@
+package invite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type InviteStore struct {
+	db    *sql.DB
+	clock types.Clock
+}
+
+func NewInviteStore(db *sql.DB, clock types.Clock) *InviteStore {
+	return &InviteStore{
+		db:    db,
+		clock: clock,
+	}
+}
+
+const selectInvitesQuery = `
+	SELECT i.*, c.name, u.name
+	FROM invites i
+	JOIN capsules c ON i.capsuleId = c.id
+	JOIN users u ON i.inviterId = u.id
+`
+
+func scanRowIntoInvite(row *sql.Rows) (*types.Invite, error) {
+	invite := new(types.Invite)
+
+	var email, phone sql.NullString
+
+	err := row.Scan(
+		&invite.ID,
+		&invite.CapsuleID,
+		&invite.InviterID,
+		&email,
+		&phone,
+		&invite.Status,
+		&invite.ExpiresAt,
+		&invite.RespondedAt,
+		&invite.CreatedAt,
+		&invite.CapsuleName,
+		&invite.InviterName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invite.Email = email.String
+	invite.Phone = phone.String
+
+	return invite, nil
+}
+
+func scanRowsIntoInvites(rows *sql.Rows) ([]types.Invite, error) {
+	invites := make([]types.Invite, 0)
+	for rows.Next() {
+		invite, err := scanRowIntoInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return invites, nil
+}
+
+// expireInvites marks pending invites past their expiry, so reads never return a stale pending invite
+func (inviteStore *InviteStore) expireInvites() error {
+	_, err := inviteStore.db.Exec("UPDATE invites SET status = 'expired' WHERE status = 'pending' AND expiresAt < ?", inviteStore.clock.Now())
+	return err
+}
+
+func (inviteStore *InviteStore) GetInviteById(inviteId uint) (*types.Invite, error) {
+	err := inviteStore.expireInvites()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := inviteStore.db.Query(selectInvitesQuery+"WHERE i.id = ?", inviteId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invite := new(types.Invite)
+	for rows.Next() {
+		invite, err = scanRowIntoInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if invite.ID != inviteId {
+		return nil, fmt.Errorf("invite not found")
+	}
+
+	return invite, nil
+}
+
+// GetPendingInvites returns the pending invites sent to an email or phone number
+func (inviteStore *InviteStore) GetPendingInvites(email string, phone string) ([]types.Invite, error) {
+	err := inviteStore.expireInvites()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := inviteStore.db.Query(selectInvitesQuery+"WHERE i.status = 'pending' AND (i.email = ? OR i.phone = ?)", email, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoInvites(rows)
+}
+
+func (inviteStore *InviteStore) GetCapsuleInvites(capsuleId uint) ([]types.Invite, error) {
+	err := inviteStore.expireInvites()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := inviteStore.db.Query(selectInvitesQuery+"WHERE i.capsuleId = ? ORDER BY i.createdAt DESC", capsuleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoInvites(rows)
+}
+
+func (inviteStore *InviteStore) CreateInvite(capsuleId uint, inviterId uint, email string, phone string) (uint, error) {
+	err := inviteStore.expireInvites()
+	if err != nil {
+		return 0, err
+	}
+
+	// check if the invitee already has a pending invite to the capsule
+	var count int
+	err = inviteStore.db.QueryRow(
+		"SELECT COUNT(*) FROM invites WHERE capsuleId = ? AND status = 'pending' AND (email = ? OR phone = ?)",
+		capsuleId, email, phone,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, fmt.Errorf("an invite to this capsule is already pending")
+	}
+
+	expiresAt := inviteStore.clock.Now().Add(time.Second * time.Duration(config.Envs.InviteExpirationInSeconds))
+
+	res, err := inviteStore.db.Exec(
+		"INSERT INTO invites (capsuleId, inviterId, email, phone, expiresAt) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)",
+		capsuleId, inviterId, email, phone, expiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
+
+func (inviteStore *InviteStore) UpdateInviteStatus(inviteId uint, status string) error {
+	_, err := inviteStore.db.Exec("UPDATE invites SET status = ?, respondedAt = ? WHERE id = ? AND status = 'pending'", status, inviteStore.clock.Now(), inviteId)
+	return err
+}
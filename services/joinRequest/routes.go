@@ -0,0 +1,216 @@
+package joinRequest
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	joinRequestStore types.JoinRequestStore
+	capsuleStore     types.CapsuleStore
+	userStore        types.UserStore
+	mailer           types.Mailer
+	preferenceStore  types.NotificationPreferenceStore
+}
+
+func NewHandler(
+	joinRequestStore types.JoinRequestStore,
+	capsuleStore types.CapsuleStore,
+	userStore types.UserStore,
+	mailer types.Mailer,
+	preferenceStore types.NotificationPreferenceStore,
+) *Handler {
+	return &Handler{
+		joinRequestStore: joinRequestStore,
+		capsuleStore:     capsuleStore,
+		userStore:        userStore,
+		mailer:           mailer,
+		preferenceStore:  preferenceStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/join-requests", auth.WithJWTAuth(handler.handleGetJoinRequests, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/join-requests/capsule/{capsuleId}", auth.WithJWTAuth(handler.handleGetCapsuleJoinRequests, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/join-requests/approve", auth.WithJWTAuth(handler.handleApproveJoinRequest, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/join-requests/reject", auth.WithJWTAuth(handler.handleRejectJoinRequest, handler.userStore)).Methods(http.MethodPost)
+}
+
+func (handler *Handler) handleGetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	joinRequests, err := handler.joinRequestStore.GetUserJoinRequests(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, joinRequests)
+}
+
+func (handler *Handler) handleGetCapsuleJoinRequests(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+	vars := mux.Vars(r)
+	capsuleIdStr, ok := vars["capsuleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("capsuleId not provided"))
+		return
+	}
+	capsuleId, err := strconv.Atoi(capsuleIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid capsuleId"))
+		return
+	}
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, uint(capsuleId), types.CapsulePermissionManageMembers)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	joinRequests, err := handler.joinRequestStore.GetCapsuleJoinRequests(uint(capsuleId))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, joinRequests)
+}
+
+// getPendingJoinRequest fetches a join request and checks that the user can respond to it
+func (handler *Handler) getPendingJoinRequest(userID uint, joinRequestID uint) (*types.JoinRequest, int, error) {
+	joinRequest, err := handler.joinRequestStore.GetJoinRequestById(joinRequestID)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, joinRequest.CapsuleID, types.CapsulePermissionManageMembers)
+	if err != nil {
+		return nil, utils.AuthorizeErrorStatus(err), err
+	}
+	if joinRequest.Status != "pending" {
+		return nil, http.StatusBadRequest, fmt.Errorf("join request has already been %s", joinRequest.Status)
+	}
+
+	return joinRequest, http.StatusOK, nil
+}
+
+// notifyRequester lets the requester know the outcome, failing to do so shouldn't undo the response
+func (handler *Handler) notifyRequester(joinRequest *types.JoinRequest) {
+	user, err := handler.userStore.GetUserById(joinRequest.UserID)
+	if err != nil {
+		log.Printf("error fetching user %d for join request %d: %v", joinRequest.UserID, joinRequest.ID, err)
+		return
+	}
+
+	mailEnabled, err := handler.preferenceStore.IsNotificationEnabled(user.ID, types.NotificationChannelEmail, types.NotificationEventJoinRequest)
+	if err != nil {
+		log.Printf("error fetching notification preferences of user %d: %v", user.ID, err)
+		return
+	}
+	if !mailEnabled {
+		return
+	}
+
+	var subject, body string
+	if joinRequest.Status == "approved" {
+		subject = "You've joined " + joinRequest.CapsuleName + "!"
+		body = "Your request to join " + joinRequest.CapsuleName + " was approved. Open our app to start adding to it!"
+	} else {
+		subject = "Your request to join " + joinRequest.CapsuleName
+		body = "Your request to join " + joinRequest.CapsuleName + " was not approved by the capsule owner."
+	}
+
+	unsubscribeLink := mail.UnsubscribeLink(user.ID, types.NotificationEventJoinRequest)
+	body += "\n\nDon't want these emails? Unsubscribe: " + unsubscribeLink
+
+	err = handler.mailer.Send(types.Mail{To: []string{user.Email}, Subject: subject, Body: body, UnsubscribeURL: unsubscribeLink})
+	if err != nil {
+		log.Printf("error notifying user %d of join request %d: %v", user.ID, joinRequest.ID, err)
+	}
+}
+
+func (handler *Handler) handleApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RespondJoinRequestPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	joinRequest, status, err := handler.getPendingJoinRequest(userID, payload.JoinRequestID)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	err = handler.capsuleStore.AddCapsuleMember(joinRequest.CapsuleID, joinRequest.UserID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = handler.joinRequestStore.UpdateJoinRequestStatus(joinRequest.ID, "approved")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	joinRequest.Status = "approved"
+	handler.notifyRequester(joinRequest)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleRejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.RespondJoinRequestPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	joinRequest, status, err := handler.getPendingJoinRequest(userID, payload.JoinRequestID)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	err = handler.joinRequestStore.UpdateJoinRequestStatus(joinRequest.ID, "rejected")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	joinRequest.Status = "rejected"
+	handler.notifyRequester(joinRequest)
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,138 @@
+package joinRequest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type JoinRequestStore struct {
+	db    *sql.DB
+	clock types.Clock
+}
+
+func NewJoinRequestStore(db *sql.DB, clock types.Clock) *JoinRequestStore {
+	return &JoinRequestStore{
+		db:    db,
+		clock: clock,
+	}
+}
+
+var ErrAlreadyRequested = errors.New("you have already requested to join this capsule")
+
+const selectJoinRequestsQuery = `
+	SELECT j.*, c.name, u.name
+	FROM joinRequests j
+	JOIN capsules c ON j.capsuleId = c.id
+	JOIN users u ON j.userId = u.id
+`
+
+func scanRowIntoJoinRequest(row *sql.Rows) (*types.JoinRequest, error) {
+	joinRequest := new(types.JoinRequest)
+
+	err := row.Scan(
+		&joinRequest.ID,
+		&joinRequest.CapsuleID,
+		&joinRequest.UserID,
+		&joinRequest.Status,
+		&joinRequest.RespondedAt,
+		&joinRequest.CreatedAt,
+		&joinRequest.CapsuleName,
+		&joinRequest.UserName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return joinRequest, nil
+}
+
+func scanRowsIntoJoinRequests(rows *sql.Rows) ([]types.JoinRequest, error) {
+	joinRequests := make([]types.JoinRequest, 0)
+	for rows.Next() {
+		joinRequest, err := scanRowIntoJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		joinRequests = append(joinRequests, *joinRequest)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return joinRequests, nil
+}
+
+func (joinRequestStore *JoinRequestStore) GetJoinRequestById(joinRequestId uint) (*types.JoinRequest, error) {
+	rows, err := joinRequestStore.db.Query(selectJoinRequestsQuery+"WHERE j.id = ?", joinRequestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	joinRequest := new(types.JoinRequest)
+	for rows.Next() {
+		joinRequest, err = scanRowIntoJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if joinRequest.ID != joinRequestId {
+		return nil, fmt.Errorf("join request not found")
+	}
+
+	return joinRequest, nil
+}
+
+func (joinRequestStore *JoinRequestStore) GetUserJoinRequests(userId uint) ([]types.JoinRequest, error) {
+	rows, err := joinRequestStore.db.Query(selectJoinRequestsQuery+"WHERE j.userId = ? ORDER BY j.createdAt DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoJoinRequests(rows)
+}
+
+func (joinRequestStore *JoinRequestStore) GetCapsuleJoinRequests(capsuleId uint) ([]types.JoinRequest, error) {
+	rows, err := joinRequestStore.db.Query(selectJoinRequestsQuery+"WHERE j.capsuleId = ? AND j.status = 'pending' ORDER BY j.createdAt", capsuleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoJoinRequests(rows)
+}
+
+func (joinRequestStore *JoinRequestStore) CreateJoinRequest(capsuleId uint, userId uint) (uint, error) {
+	// check if the user is already waiting on the owner
+	var count int
+	err := joinRequestStore.db.QueryRow("SELECT COUNT(*) FROM joinRequests WHERE capsuleId = ? AND userId = ? AND status = 'pending'", capsuleId, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrAlreadyRequested
+	}
+
+	res, err := joinRequestStore.db.Exec("INSERT INTO joinRequests (capsuleId, userId) VALUES (?, ?)", capsuleId, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
+
+func (joinRequestStore *JoinRequestStore) UpdateJoinRequestStatus(joinRequestId uint, status string) error {
+	_, err := joinRequestStore.db.Exec("UPDATE joinRequests SET status = ?, respondedAt = ? WHERE id = ? AND status = 'pending'", status, joinRequestStore.clock.Now(), joinRequestId)
+	return err
+}
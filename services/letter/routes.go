@@ -0,0 +1,156 @@
+package letter
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	capsuleStore types.CapsuleStore
+	userStore    types.UserStore
+	letterStore  types.LetterStore
+}
+
+func NewHandler(capsuleStore types.CapsuleStore, userStore types.UserStore, letterStore types.LetterStore) *Handler {
+	return &Handler{
+		capsuleStore: capsuleStore,
+		userStore:    userStore,
+		letterStore:  letterStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/letters/create", auth.WithJWTAuth(handler.handleCreateLetter, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/letters/update", auth.WithJWTAuth(handler.handleUpdateLetter, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/letters/delete", auth.WithJWTAuth(handler.handleDeleteLetter, handler.userStore)).Methods(http.MethodPost)
+}
+
+func isCapsuleMember(capsule types.Capsule, userID uint) bool {
+	for _, member := range capsule.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (handler *Handler) handleCreateLetter(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.CreateLetterPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	// check if user can change the capsule contents
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+	if !isCapsuleMember(capsule, payload.RecipientID) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("letters can only be written to members of the capsule"))
+		return
+	}
+
+	unlockAt, err := utils.ParseDateToOpen(payload.UnlockAt, capsule.Timezone)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid date to unlock the letter"))
+		return
+	}
+
+	letterID, err := handler.letterStore.CreateLetter(userID, payload.CapsuleID, payload.RecipientID, unlockAt, payload.Letter)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]uint{"id": letterID})
+}
+
+func (handler *Handler) handleUpdateLetter(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.UpdateLetterPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	// check if user can change the capsule contents
+	capsule, err := handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	unlockAt, err := utils.ParseDateToOpen(payload.UnlockAt, capsule.Timezone)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid date to unlock the letter"))
+		return
+	}
+
+	err = handler.letterStore.UpdateLetter(userID, payload.CapsuleID, payload.LetterID, unlockAt, payload.Letter)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleDeleteLetter(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.DeleteLetterPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
+	err = handler.letterStore.DeleteLetter(userID, payload.CapsuleID, payload.LetterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,95 @@
+package letter
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type LetterStore struct {
+	db *sql.DB
+}
+
+func NewLetterStore(db *sql.DB) *LetterStore {
+	return &LetterStore{
+		db: db,
+	}
+}
+
+func scanRowIntoLetter(row *sql.Rows) (*types.Letter, error) {
+	letter := new(types.Letter)
+
+	err := row.Scan(
+		&letter.ID,
+		&letter.UserID,
+		&letter.CapsuleID,
+		&letter.RecipientID,
+		&letter.UnlockAt,
+		&letter.Letter,
+		&letter.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, nil
+}
+
+// GetLetters returns the letters the user wrote, and the ones written to them that have unlocked by now.
+// letters never unlock before the capsule is opened
+func (letterStore *LetterStore) GetLetters(capsuleID uint, userID uint, now time.Time) ([]types.Letter, error) {
+	getLettersQuery := `
+		SELECT l.*
+		FROM letters l
+		JOIN capsules c ON l.capsuleId = c.id
+		WHERE l.capsuleId = ? AND (l.userId = ? OR (l.recipientId = ? AND l.unlockAt <= ? AND c.sealed = 'opened'))
+		ORDER BY l.unlockAt, l.id
+	`
+	rows, err := letterStore.db.Query(getLettersQuery, capsuleID, userID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := make([]types.Letter, 0)
+	for rows.Next() {
+		letter, err := scanRowIntoLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *letter)
+	}
+
+	return letters, nil
+}
+
+func (letterStore *LetterStore) CreateLetter(userID uint, capsuleID uint, recipientID uint, unlockAt time.Time, letter string) (uint, error) {
+	res, err := letterStore.db.Exec(
+		"INSERT INTO letters (userId, capsuleId, recipientId, unlockAt, letter) VALUES (?, ?, ?, ?, ?)",
+		userID, capsuleID, recipientID, unlockAt, letter,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
+
+func (letterStore *LetterStore) UpdateLetter(userID uint, capsuleID uint, letterID uint, unlockAt time.Time, letter string) error {
+	_, err := letterStore.db.Exec(
+		"UPDATE letters SET unlockAt = ?, letter = ? WHERE id = ? AND userId = ? AND capsuleId = ?",
+		unlockAt, letter, letterID, userID, capsuleID,
+	)
+	return err
+}
+
+func (letterStore *LetterStore) DeleteLetter(userID uint, capsuleID uint, letterID uint) error {
+	_, err := letterStore.db.Exec("DELETE FROM letters WHERE id = ? AND userId = ? AND capsuleId = ?", letterID, userID, capsuleID)
+	return err
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// FileMailer writes each mail to an .eml file instead of sending it, for local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (mailer *FileMailer) Send(mail types.Mail) error {
+	msg, err := formatMessage(mailer.from, mail)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(mailer.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(mailer.dir, fileName), msg, 0644)
+}
@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// NewMailer picks the mail backend from the environment
+func NewMailer() types.Mailer {
+	switch config.Envs.MailBackend {
+	case "file":
+		return NewFileMailer(config.Envs.MailDropDir, config.Envs.MailFrom)
+	case "memory":
+		return NewMemoryMailer()
+	case "smtp":
+		return NewSMTPMailer(config.Envs.SMTPHost, config.Envs.SMTPPort, config.Envs.SMTPUsername, config.Envs.SMTPPassword, config.Envs.MailFrom)
+	default:
+		log.Fatalf("Unknown mail backend: %s", config.Envs.MailBackend)
+		return nil
+	}
+}
+
+// formatMessage renders the mail as an RFC 5322 message, with a multipart/alternative body when there is an html version
+func formatMessage(from string, mail types.Mail) ([]byte, error) {
+	to := "undisclosed-recipients:;"
+	if len(mail.To) == 1 {
+		to = mail.To[0]
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if mail.UnsubscribeURL != "" {
+		fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", mail.UnsubscribeURL)
+	}
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	if mail.HTMLBody == "" {
+		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		err := writeQuotedPrintable(&msg, mail.Body)
+		return msg.Bytes(), err
+	}
+
+	writer := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	// parts go from least to most preferred
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", mail.Body},
+		{"text/html; charset=UTF-8", mail.HTMLBody},
+	}
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPrintable(partWriter, part.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writer.Close()
+	return msg.Bytes(), err
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qpWriter := quotedprintable.NewWriter(w)
+	_, err := qpWriter.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	if err != nil {
+		return err
+	}
+	return qpWriter.Close()
+}
@@ -0,0 +1,164 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	netMail "net/mail"
+	"strings"
+	"testing"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+func parseMessage(t *testing.T, mail types.Mail) *netMail.Message {
+	t.Helper()
+	raw, err := formatMessage("Retrospect <hello@retrospect.test>", mail)
+	if err != nil {
+		t.Fatalf("formatMessage: %v", err)
+	}
+	msg, err := netMail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("reading formatted message: %v\n%s", err, raw)
+	}
+	return msg
+}
+
+func TestFormatMessageMultipart(t *testing.T) {
+	msg := parseMessage(t, types.Mail{
+		To:       []string{"ana@retrospect.test"},
+		Subject:  "Your capsule is ready 🎉",
+		Body:     "Hi Ana,\nit's time to open it.",
+		HTMLBody: "<p>Hi Ana,</p><p>it's time to open it.</p>",
+	})
+
+	if to := msg.Header.Get("To"); to != "ana@retrospect.test" {
+		t.Errorf("To = %q", to)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your capsule is ready 🎉" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	// the plaintext part has to come first, clients show the last part they understand
+	want := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", "Hi Ana,\r\nit's time to open it."},
+		{"text/html", "<p>Hi Ana,</p><p>it's time to open it.</p>"},
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for i, w := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partType != w.contentType {
+			t.Errorf("part %d Content-Type = %q, want %q", i, partType, w.contentType)
+		}
+		// multipart.Reader decodes quoted-printable parts itself
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading part %d: %v", i, err)
+		}
+		if string(body) != w.body {
+			t.Errorf("part %d body = %q, want %q", i, body, w.body)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("expected two parts, got another: %v", err)
+	}
+}
+
+func TestFormatMessagePlaintextOnly(t *testing.T) {
+	msg := parseMessage(t, types.Mail{
+		To:      []string{"ana@retrospect.test", "ben@retrospect.test"},
+		Subject: "Reset your password",
+		Body:    "Follow the link to reset your password.",
+	})
+
+	// several recipients are only ever sent as bcc
+	if to := msg.Header.Get("To"); to != "undisclosed-recipients:;" {
+		t.Errorf("To = %q", to)
+	}
+	mediaType, _, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "text/plain" {
+		t.Errorf("Content-Type = %q, want text/plain", mediaType)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "Follow the link to reset your password.") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestFormatMessageUnsubscribeHeader(t *testing.T) {
+	msg := parseMessage(t, types.Mail{
+		To:             []string{"ana@retrospect.test"},
+		Subject:        "Countdown",
+		Body:           "3 days left",
+		UnsubscribeURL: "http://localhost/unsubscribe?token=abc",
+	})
+	if header := msg.Header.Get("List-Unsubscribe"); header != "<http://localhost/unsubscribe?token=abc>" {
+		t.Errorf("List-Unsubscribe = %q", header)
+	}
+
+	msg = parseMessage(t, types.Mail{
+		To:      []string{"ana@retrospect.test"},
+		Subject: "Reset your password",
+		Body:    "Follow the link.",
+	})
+	if _, ok := msg.Header["List-Unsubscribe"]; ok {
+		t.Error("mail that can't be turned off shouldn't have a List-Unsubscribe header")
+	}
+}
+
+func TestRenderMail(t *testing.T) {
+	data := TemplateData{
+		RecipientName:   "Ana",
+		ActorName:       "Ben",
+		CapsuleName:     "Summer <2026>",
+		Vessel:          "bottle",
+		DateToOpen:      "January 1, 2027",
+		DaysLeft:        3,
+		Link:            "http://localhost/capsules/1",
+		UnsubscribeLink: UnsubscribeLink(1, types.NotificationEventCapsuleInvite),
+	}
+
+	namedCapsule := 0
+	for name := range textTemplates {
+		mail, err := RenderMail([]string{"ana@retrospect.test"}, name, data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if strings.TrimSpace(mail.Subject) == "" || strings.TrimSpace(mail.Body) == "" || strings.TrimSpace(mail.HTMLBody) == "" {
+			t.Errorf("%s: subject, body and html body should all be filled in", name)
+		}
+		if mail.UnsubscribeURL != data.UnsubscribeLink {
+			t.Errorf("%s: UnsubscribeURL = %q", name, mail.UnsubscribeURL)
+		}
+		if strings.Contains(mail.HTMLBody, "Summer <2026>") {
+			t.Errorf("%s: html body doesn't escape the capsule name", name)
+		}
+		if strings.Contains(mail.HTMLBody, "Summer &lt;2026&gt;") {
+			namedCapsule++
+		}
+	}
+	if namedCapsule == 0 {
+		t.Error("no template showed the capsule name")
+	}
+}
+
+func TestRenderMailUnknownTemplate(t *testing.T) {
+	if _, err := RenderMail([]string{"ana@retrospect.test"}, "no-such-template", TemplateData{}); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"sync"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// MemoryMailer records mail instead of sending it, for tests
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []types.Mail
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mailer *MemoryMailer) Send(mail types.Mail) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	mailer.sent = append(mailer.sent, mail)
+	return nil
+}
+
+// Sent returns every mail recorded so far
+func (mailer *MemoryMailer) Sent() []types.Mail {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	sent := make([]types.Mail, len(mailer.sent))
+	copy(sent, mailer.sent)
+	return sent
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (mailer *SMTPMailer) Send(mail types.Mail) error {
+	msg, err := formatMessage(mailer.from, mail)
+	if err != nil {
+		return err
+	}
+
+	auth := smtp.PlainAuth(
+		"",
+		mailer.username,
+		mailer.password,
+		mailer.host,
+	)
+
+	return smtp.SendMail(
+		net.JoinHostPort(mailer.host, mailer.port),
+		auth,
+		mailer.from,
+		mail.To,
+		msg,
+	)
+}
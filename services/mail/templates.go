@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"net/url"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+)
+
+const (
+	TemplateCapsuleReady       = "capsule-ready"
+	TemplateCapsuleSealed      = "capsule-sealed"
+	TemplateCapsuleCountdown   = "capsule-countdown"
+	TemplateCapsuleAnniversary = "capsule-anniversary"
+	TemplateCapsuleInvite      = "capsule-invite"
+	TemplateMemberSealed       = "member-sealed"
+	TemplateSealNudge          = "seal-nudge"
+	TemplateLetterUnlocked     = "letter-unlocked"
+	TemplateGiftReady          = "gift-ready"
+	TemplatePasswordReset      = "password-reset"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// every template has a plaintext version defining "subject" and "body", and an html version of the body.
+// they're parsed separately since each plaintext version defines the same template names
+var (
+	textTemplates = make(map[string]*textTemplate.Template)
+	htmlTemplates = make(map[string]*htmlTemplate.Template)
+)
+
+func init() {
+	names := []string{
+		TemplateCapsuleReady,
+		TemplateCapsuleSealed,
+		TemplateCapsuleCountdown,
+		TemplateCapsuleAnniversary,
+		TemplateCapsuleInvite,
+		TemplateMemberSealed,
+		TemplateSealNudge,
+		TemplateLetterUnlocked,
+		TemplateGiftReady,
+		TemplatePasswordReset,
+	}
+	for _, name := range names {
+		textTemplates[name] = textTemplate.Must(textTemplate.ParseFS(templateFS, "templates/"+name+".txt"))
+		htmlTemplates[name] = htmlTemplate.Must(htmlTemplate.ParseFS(templateFS, "templates/"+name+".html"))
+	}
+}
+
+type TemplateData struct {
+	RecipientName string
+	ActorName     string // whoever caused the mail to be sent, eg. the inviter
+	CapsuleName   string
+	Vessel        string
+	DateToOpen    string
+	DaysLeft      int // until the capsule opens, for countdowns
+	Link          string
+
+	UnsubscribeLink string // left empty for mail that can't be turned off, eg. password resets
+}
+
+// CapsuleTemplateData fills in the capsule details shared by all capsule mail, dates are shown in the capsule's timezone
+func CapsuleTemplateData(capsuleId uint, name string, vessel string, dateToOpen *time.Time, timezone string) TemplateData {
+	data := TemplateData{
+		CapsuleName: name,
+		Vessel:      vessel,
+		Link:        CapsuleLink(capsuleId),
+	}
+	if dateToOpen != nil {
+		local := dateToOpen.In(utils.LoadLocation(timezone))
+		data.DateToOpen = local.Format("January 2, 2006")
+		if local.Hour() != 0 || local.Minute() != 0 {
+			data.DateToOpen = local.Format("January 2, 2006 at 3:04 PM MST")
+		}
+	}
+	return data
+}
+
+func CapsuleLink(capsuleId uint) string {
+	return fmt.Sprintf("%s/capsules/%d", config.Envs.PublicHost, capsuleId)
+}
+
+// GiftLink shows the opened capsule to a gift recipient, it works without logging in
+func GiftLink(recipientId uint) string {
+	return config.Envs.PublicHost + "/gifts?token=" + url.QueryEscape(auth.CreateGiftToken(recipientId))
+}
+
+// UnsubscribeLink turns off the event's email for the user, it works without logging in
+func UnsubscribeLink(userId uint, event string) string {
+	return config.Envs.PublicHost + "/unsubscribe?token=" + url.QueryEscape(auth.CreateUnsubscribeToken(userId, event))
+}
+
+// RenderMail builds a mail to the recipients from the named template
+func RenderMail(to []string, name string, data TemplateData) (types.Mail, error) {
+	text, ok := textTemplates[name]
+	html := htmlTemplates[name]
+	if !ok {
+		return types.Mail{}, fmt.Errorf("mail template %s not found", name)
+	}
+
+	var subject, body, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return types.Mail{}, err
+	}
+	if err := text.ExecuteTemplate(&body, "body", data); err != nil {
+		return types.Mail{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return types.Mail{}, err
+	}
+
+	return types.Mail{
+		To:       to,
+		Subject:  subject.String(),
+		Body:     body.String(),
+		HTMLBody: htmlBody.String(),
+
+		UnsubscribeURL: data.UnsubscribeLink,
+	}, nil
+}
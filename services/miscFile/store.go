@@ -35,8 +35,11 @@ func scanRowIntoMiscFile(row *sql.Rows) (*types.MiscFile, error) {
 	return miscFile, nil
 }
 
-func (miscFileStore *MiscFileStore) GetMiscFiles(capsuleID uint) ([]types.MiscFile, error) {
-	rows, err := miscFileStore.db.Query("SELECT * FROM miscFiles WHERE capsuleId = ?", capsuleID)
+func (miscFileStore *MiscFileStore) GetMiscFiles(capsuleID uint, userID uint) ([]types.MiscFile, error) {
+	rows, err := miscFileStore.db.Query(
+		"SELECT f.* FROM miscFiles f JOIN capsules c ON f.capsuleId = c.id WHERE f.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR f.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
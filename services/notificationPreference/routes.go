@@ -0,0 +1,106 @@
+package notificationPreference
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	preferenceStore types.NotificationPreferenceStore
+	userStore       types.UserStore
+}
+
+func NewHandler(preferenceStore types.NotificationPreferenceStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		preferenceStore: preferenceStore,
+		userStore:       userStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notification-preferences", auth.WithJWTAuth(handler.handleGetNotificationPreferences, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/notification-preferences/update", auth.WithJWTAuth(handler.handleUpdateNotificationPreference, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/unsubscribe", handler.handleUnsubscribe).Methods(http.MethodPost)
+}
+
+func (handler *Handler) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	preferences, err := handler.preferenceStore.GetNotificationPreferences(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, preferences)
+}
+
+func (handler *Handler) handleUpdateNotificationPreference(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.UpdateNotificationPreferencePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	err = handler.preferenceStore.SetNotificationPreference(userID, payload.Channel, payload.Event, *payload.Enabled)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleUnsubscribe turns off an email from the link at the bottom of it, so there's no login
+func (handler *Handler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.UnsubscribePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	userID, event, err := auth.VerifyUnsubscribeToken(payload.Token)
+	if err != nil {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+	if !slices.Contains(types.NotificationEvents, event) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown notification %s", event))
+		return
+	}
+
+	err = handler.preferenceStore.SetNotificationPreference(userID, types.NotificationChannelEmail, event, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"channel": types.NotificationChannelEmail, "event": event})
+}
@@ -0,0 +1,77 @@
+package notificationPreference
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type NotificationPreferenceStore struct {
+	db *sql.DB
+}
+
+func NewNotificationPreferenceStore(db *sql.DB) *NotificationPreferenceStore {
+	return &NotificationPreferenceStore{
+		db: db,
+	}
+}
+
+// GetNotificationPreferences returns every channel and event, with the ones the user hasn't changed left on
+func (preferenceStore *NotificationPreferenceStore) GetNotificationPreferences(userId uint) ([]types.NotificationPreference, error) {
+	rows, err := preferenceStore.db.Query("SELECT channel, event, enabled FROM notificationPreferences WHERE userId = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := make(map[string]bool)
+	for rows.Next() {
+		var channel, event string
+		var enabled bool
+		if err := rows.Scan(&channel, &event, &enabled); err != nil {
+			return nil, err
+		}
+		saved[channel+"."+event] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	preferences := make([]types.NotificationPreference, 0, len(types.NotificationChannels)*len(types.NotificationEvents))
+	for _, channel := range types.NotificationChannels {
+		for _, event := range types.NotificationEvents {
+			enabled, ok := saved[channel+"."+event]
+			preferences = append(preferences, types.NotificationPreference{
+				Channel: channel,
+				Event:   event,
+				Enabled: enabled || !ok,
+			})
+		}
+	}
+
+	return preferences, nil
+}
+
+func (preferenceStore *NotificationPreferenceStore) SetNotificationPreference(userId uint, channel string, event string, enabled bool) error {
+	_, err := preferenceStore.db.Exec(
+		"INSERT INTO notificationPreferences (userId, channel, event, enabled) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)",
+		userId, channel, event, enabled,
+	)
+	return err
+}
+
+func (preferenceStore *NotificationPreferenceStore) IsNotificationEnabled(userId uint, channel string, event string) (bool, error) {
+	var enabled bool
+	err := preferenceStore.db.QueryRow(
+		"SELECT enabled FROM notificationPreferences WHERE userId = ? AND channel = ? AND event = ?",
+		userId, channel, event,
+	).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import "github.com/TenacityLabs/retrospect-backend/types"
+
+// Mailer queues mail in the outbox instead of sending it, so requests don't wait on or lose mail to a flaky server
+type Mailer struct {
+	outboxStore types.OutboxStore
+}
+
+func NewMailer(outboxStore types.OutboxStore) *Mailer {
+	return &Mailer{
+		outboxStore: outboxStore,
+	}
+}
+
+func (mailer *Mailer) Send(mail types.Mail) error {
+	_, err := mailer.outboxStore.EnqueueMail(mail, nil, "")
+	return err
+}
@@ -0,0 +1,18 @@
+package outbox
+
+import "github.com/TenacityLabs/retrospect-backend/types"
+
+// Pusher queues a push to each of a user's devices in the outbox
+type Pusher struct {
+	outboxStore types.OutboxStore
+}
+
+func NewPusher(outboxStore types.OutboxStore) *Pusher {
+	return &Pusher{
+		outboxStore: outboxStore,
+	}
+}
+
+func (pusher *Pusher) Push(userId uint, push types.Push) error {
+	return pusher.outboxStore.EnqueuePush(userId, push, "")
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// most jobs returned when listing the outbox
+const listLimit = 100
+
+type Handler struct {
+	outboxStore types.OutboxStore
+}
+
+func NewHandler(outboxStore types.OutboxStore) *Handler {
+	return &Handler{
+		outboxStore: outboxStore,
+	}
+}
+
+func (handler *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/outbox", auth.WithAdminAPIKey(handler.handleGetJobs)).Methods(http.MethodGet)
+	router.HandleFunc("/outbox/{jobId}", auth.WithAdminAPIKey(handler.handleGetJobById)).Methods(http.MethodGet)
+	router.HandleFunc("/outbox/replay", auth.WithAdminAPIKey(handler.handleReplayJob)).Methods(http.MethodPost)
+}
+
+// handleGetJobs lists jobs by status, dead jobs by default since those are the ones that need attention
+func (handler *Handler) handleGetJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "dead"
+	}
+	if status != "pending" && status != "processing" && status != "sent" && status != "dead" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %s", status))
+		return
+	}
+
+	jobs, err := handler.outboxStore.GetJobs(status, listLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, jobs)
+}
+
+func (handler *Handler) handleGetJobById(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	jobIdStr, ok := vars["jobId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("jobId not provided"))
+		return
+	}
+	jobId, err := strconv.Atoi(jobIdStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid jobId"))
+		return
+	}
+
+	job, err := handler.outboxStore.GetJobById(uint(jobId))
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job)
+}
+
+func (handler *Handler) handleReplayJob(w http.ResponseWriter, r *http.Request) {
+	// get json payload
+	var payload types.ReplayOutboxJobPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	err = handler.outboxStore.ReplayJob(payload.JobID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,264 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+const (
+	KindMail = "mail"
+	KindPush = "push"
+)
+
+// pushPayload is what's stored for a push job, the device is looked up again when it's sent in case it was removed
+type pushPayload struct {
+	DeviceID uint       `json:"deviceId"`
+	Push     types.Push `json:"push"`
+}
+
+// longest a failed job waits before its next attempt
+const maxBackoff = 6 * time.Hour
+
+// jobs claimed for longer than this are assumed to belong to a crashed worker and are picked up again
+const claimTimeout = 10 * time.Minute
+
+type OutboxStore struct {
+	db *sql.DB
+}
+
+func NewOutboxStore(db *sql.DB) *OutboxStore {
+	return &OutboxStore{
+		db: db,
+	}
+}
+
+func scanRowIntoOutboxJob(row *sql.Rows) (*types.OutboxJob, error) {
+	job := new(types.OutboxJob)
+
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.Payload,
+		&job.DeliveryID,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.LastError,
+		&job.RunAt,
+		&job.ClaimedBy,
+		&job.ClaimedAt,
+		&job.SentAt,
+		&job.CreatedAt,
+		&job.JobKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func scanRowsIntoOutboxJobs(rows *sql.Rows) ([]types.OutboxJob, error) {
+	jobs := make([]types.OutboxJob, 0)
+	for rows.Next() {
+		job, err := scanRowIntoOutboxJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// backoff doubles the wait after every failed attempt
+func backoff(attempts uint) time.Duration {
+	delay := time.Second * time.Duration(config.Envs.OutboxBackoffInSeconds)
+	for i := uint(1); i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// EnqueueMail stores a mail to be sent by the workers, a delivery or job key is only ever queued once,
+// an empty job key lets the mail be queued again
+func (outboxStore *OutboxStore) EnqueueMail(mail types.Mail, deliveryId *uint, jobKey string) (uint, error) {
+	payload, err := json.Marshal(mail)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := outboxStore.db.Exec(
+		"INSERT INTO outbox (kind, payload, deliveryId, maxAttempts, jobKey) VALUES (?, ?, ?, ?, NULLIF(?, '')) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)",
+		KindMail, payload, deliveryId, config.Envs.MailAttempts, jobKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	jobId, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint(jobId), nil
+}
+
+// EnqueuePush stores a push for each of the user's devices, so every device is retried on its own
+// nothing is queued if the user turned off pushes for the event. with a job key each device only ever gets the push once
+func (outboxStore *OutboxStore) EnqueuePush(userId uint, push types.Push, jobKey string) error {
+	pushJSON, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+
+	enqueuePushQuery := `
+		INSERT INTO outbox (kind, payload, maxAttempts, jobKey)
+		SELECT ?, JSON_OBJECT('deviceId', d.id, 'push', CAST(? AS JSON)), ?, IF(? = '', NULL, CONCAT(?, '-device-', d.id))
+		FROM devices d
+		WHERE d.userId = ? AND NOT EXISTS (
+			SELECT 1 FROM notificationPreferences p
+			WHERE p.userId = d.userId AND p.channel = 'push' AND p.event = ? AND p.enabled = FALSE
+		)
+		ON DUPLICATE KEY UPDATE id = id
+	`
+	_, err = outboxStore.db.Exec(enqueuePushQuery, KindPush, string(pushJSON), config.Envs.MailAttempts, jobKey, jobKey, userId, push.Data["event"])
+	return err
+}
+
+// ClaimJobs marks up to limit due jobs as being processed by the worker and returns them
+func (outboxStore *OutboxStore) ClaimJobs(workerId string, limit int) ([]types.OutboxJob, error) {
+	claimJobsQuery := `
+		UPDATE outbox
+		SET status = 'processing', claimedBy = ?, claimedAt = NOW()
+		WHERE (status = 'pending' AND runAt <= NOW())
+			OR (status = 'processing' AND claimedAt < NOW() - INTERVAL ? SECOND)
+		ORDER BY runAt, id
+		LIMIT ?
+	`
+	_, err := outboxStore.db.Exec(claimJobsQuery, workerId, int64(claimTimeout.Seconds()), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := outboxStore.db.Query("SELECT * FROM outbox WHERE status = 'processing' AND claimedBy = ? ORDER BY runAt, id", workerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoOutboxJobs(rows)
+}
+
+func (outboxStore *OutboxStore) CompleteJob(job types.OutboxJob) error {
+	_, err := outboxStore.db.Exec(
+		"UPDATE outbox SET status = 'sent', attempts = attempts + 1, sentAt = NOW(), claimedBy = NULL WHERE id = ?",
+		job.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if job.DeliveryID != nil {
+		_, err = outboxStore.db.Exec(
+			"UPDATE capsuleDeliveries SET status = 'sent', attempts = ?, sentAt = NOW() WHERE id = ?",
+			job.Attempts+1, *job.DeliveryID,
+		)
+	}
+	return err
+}
+
+// FailJob schedules the job to be retried with backoff, or dead letters it once it's out of attempts
+func (outboxStore *OutboxStore) FailJob(job types.OutboxJob, sendErr error) error {
+	attempts := job.Attempts + 1
+	if attempts < job.MaxAttempts {
+		_, err := outboxStore.db.Exec(
+			"UPDATE outbox SET status = 'pending', attempts = ?, lastError = ?, runAt = NOW() + INTERVAL ? SECOND, claimedBy = NULL WHERE id = ?",
+			attempts, sendErr.Error(), int64(backoff(attempts).Seconds()), job.ID,
+		)
+		return err
+	}
+
+	_, err := outboxStore.db.Exec(
+		"UPDATE outbox SET status = 'dead', attempts = ?, lastError = ?, claimedBy = NULL WHERE id = ?",
+		attempts, sendErr.Error(), job.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if job.DeliveryID != nil {
+		_, err = outboxStore.db.Exec(
+			"UPDATE capsuleDeliveries SET status = 'failed', attempts = ?, lastError = ? WHERE id = ?",
+			attempts, sendErr.Error(), *job.DeliveryID,
+		)
+	}
+	return err
+}
+
+func (outboxStore *OutboxStore) GetJobs(status string, limit int) ([]types.OutboxJob, error) {
+	rows, err := outboxStore.db.Query("SELECT * FROM outbox WHERE status = ? ORDER BY id DESC LIMIT ?", status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowsIntoOutboxJobs(rows)
+}
+
+func (outboxStore *OutboxStore) GetJobById(jobId uint) (*types.OutboxJob, error) {
+	rows, err := outboxStore.db.Query("SELECT * FROM outbox WHERE id = ?", jobId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	job := new(types.OutboxJob)
+	for rows.Next() {
+		job, err = scanRowIntoOutboxJob(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if job.ID != jobId {
+		return nil, fmt.Errorf("outbox job not found")
+	}
+
+	return job, nil
+}
+
+// ReplayJob gives a dead job a fresh set of attempts
+func (outboxStore *OutboxStore) ReplayJob(jobId uint) error {
+	job, err := outboxStore.GetJobById(jobId)
+	if err != nil {
+		return err
+	}
+	if job.Status != "dead" {
+		return fmt.Errorf("only dead jobs can be replayed, job is %s", job.Status)
+	}
+
+	_, err = outboxStore.db.Exec(
+		"UPDATE outbox SET status = 'pending', attempts = 0, runAt = NOW() WHERE id = ?",
+		jobId,
+	)
+	if err != nil {
+		return err
+	}
+
+	if job.DeliveryID != nil {
+		_, err = outboxStore.db.Exec("UPDATE capsuleDeliveries SET status = 'queued' WHERE id = ?", *job.DeliveryID)
+	}
+	return err
+}
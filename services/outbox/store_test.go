@@ -0,0 +1,253 @@
+package outbox
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// the recording driver stands in for mysql, it keeps every statement run against it and answers
+// queries with the rows the test scripted
+func init() {
+	sql.Register("outboxtest", recordingDriver{})
+}
+
+type recordedStatement struct {
+	query string
+	args  []driver.Value
+}
+
+type recordingDB struct {
+	mu         sync.Mutex
+	statements []recordedStatement
+	rows       [][]driver.Value // returned by the next query
+}
+
+var recordingDBs sync.Map
+
+func newRecordingDB(t *testing.T) (*sql.DB, *recordingDB) {
+	t.Helper()
+	recording := &recordingDB{}
+	recordingDBs.Store(t.Name(), recording)
+	t.Cleanup(func() { recordingDBs.Delete(t.Name()) })
+
+	db, err := sql.Open("outboxtest", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, recording
+}
+
+// execs returns the updates and inserts run so far
+func (recording *recordingDB) execs() []recordedStatement {
+	recording.mu.Lock()
+	defer recording.mu.Unlock()
+
+	execs := make([]recordedStatement, 0)
+	for _, statement := range recording.statements {
+		if !strings.HasPrefix(strings.TrimSpace(statement.query), "SELECT") {
+			execs = append(execs, statement)
+		}
+	}
+	return execs
+}
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(name string) (driver.Conn, error) {
+	recording, ok := recordingDBs.Load(name)
+	if !ok {
+		return nil, errors.New("no recording database " + name)
+	}
+	return &recordingConn{recording.(*recordingDB)}, nil
+}
+
+type recordingConn struct {
+	recording *recordingDB
+}
+
+func (conn *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{recording: conn.recording, query: query}, nil
+}
+func (conn *recordingConn) Close() error              { return nil }
+func (conn *recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct {
+	recording *recordingDB
+	query     string
+}
+
+func (stmt *recordingStmt) Close() error  { return nil }
+func (stmt *recordingStmt) NumInput() int { return -1 }
+
+func (stmt *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	stmt.recording.mu.Lock()
+	defer stmt.recording.mu.Unlock()
+	stmt.recording.statements = append(stmt.recording.statements, recordedStatement{stmt.query, args})
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	stmt.recording.mu.Lock()
+	defer stmt.recording.mu.Unlock()
+	stmt.recording.statements = append(stmt.recording.statements, recordedStatement{stmt.query, args})
+	rows := stmt.recording.rows
+	stmt.recording.rows = nil
+	return &recordingRows{rows: rows}, nil
+}
+
+type recordingRows struct {
+	rows [][]driver.Value
+}
+
+func (rows *recordingRows) Columns() []string {
+	// the outbox table's columns, SELECT * scans them by position
+	return []string{"id", "kind", "payload", "deliveryId", "status", "attempts", "maxAttempts", "lastError", "runAt", "claimedBy", "claimedAt", "sentAt", "createdAt", "jobKey"}
+}
+func (rows *recordingRows) Close() error { return nil }
+
+func (rows *recordingRows) Next(dest []driver.Value) error {
+	if len(rows.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.rows[0])
+	rows.rows = rows.rows[1:]
+	return nil
+}
+
+func jobRow(id int64, kind string, payload string, deliveryId any, attempts int64, maxAttempts int64) []driver.Value {
+	now := time.Now()
+	return []driver.Value{id, kind, []byte(payload), deliveryId, "processing", attempts, maxAttempts, nil, now, "worker-1", now, nil, now, nil}
+}
+
+func TestBackoff(t *testing.T) {
+	backoffInSeconds := config.Envs.OutboxBackoffInSeconds
+	config.Envs.OutboxBackoffInSeconds = 30
+	defer func() { config.Envs.OutboxBackoffInSeconds = backoffInSeconds }()
+
+	want := map[uint]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		4:  4 * time.Minute,
+		10: 256 * time.Minute,
+		11: maxBackoff,
+		50: maxBackoff,
+	}
+	for attempts, delay := range want {
+		if got := backoff(attempts); got != delay {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, delay)
+		}
+	}
+}
+
+func TestClaimJobs(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	recording.rows = [][]driver.Value{
+		jobRow(1, KindMail, `{"to":["ana@retrospect.test"]}`, int64(9), 0, 5),
+		jobRow(2, KindPush, `{"deviceId":3}`, nil, 2, 5),
+	}
+
+	jobs, err := NewOutboxStore(db).ClaimJobs("worker-1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != 1 || jobs[1].ID != 2 {
+		t.Fatalf("claimed %+v", jobs)
+	}
+	if jobs[0].DeliveryID == nil || *jobs[0].DeliveryID != 9 || jobs[1].DeliveryID != nil {
+		t.Errorf("delivery ids weren't scanned: %v, %v", jobs[0].DeliveryID, jobs[1].DeliveryID)
+	}
+
+	execs := recording.execs()
+	if len(execs) != 1 {
+		t.Fatalf("expected one claiming update, got %d", len(execs))
+	}
+	// jobs stuck with a crashed worker are taken over after the claim timeout
+	claim := execs[0]
+	if !strings.Contains(claim.query, "status = 'processing' AND claimedAt < NOW() - INTERVAL ? SECOND") {
+		t.Errorf("claim doesn't take over stale jobs: %s", claim.query)
+	}
+	if claim.args[0] != "worker-1" || claim.args[1] != int64(claimTimeout.Seconds()) || claim.args[2] != int64(10) {
+		t.Errorf("claim args = %v", claim.args)
+	}
+}
+
+func TestFailJobRetriesWithBackoff(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	job := types.OutboxJob{ID: 5, Kind: KindMail, Attempts: 1, MaxAttempts: 5}
+
+	err := NewOutboxStore(db).FailJob(job, errors.New("smtp unavailable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execs := recording.execs()
+	if len(execs) != 1 || !strings.Contains(execs[0].query, "status = 'pending'") {
+		t.Fatalf("expected the job to go back to pending, got %+v", execs)
+	}
+	wantArgs := []driver.Value{int64(2), "smtp unavailable", int64(backoff(2).Seconds()), int64(5)}
+	for i, arg := range wantArgs {
+		if execs[0].args[i] != arg {
+			t.Errorf("arg %d = %v, want %v", i, execs[0].args[i], arg)
+		}
+	}
+}
+
+func TestFailJobDeadLettersOnLastAttempt(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	deliveryId := uint(9)
+	job := types.OutboxJob{ID: 5, Kind: KindMail, Attempts: 4, MaxAttempts: 5, DeliveryID: &deliveryId}
+
+	err := NewOutboxStore(db).FailJob(job, errors.New("mailbox full"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execs := recording.execs()
+	if len(execs) != 2 {
+		t.Fatalf("expected the job and its delivery to be updated, got %+v", execs)
+	}
+	if !strings.Contains(execs[0].query, "status = 'dead'") || execs[0].args[0] != int64(5) || execs[0].args[2] != int64(5) {
+		t.Errorf("job wasn't dead lettered: %+v", execs[0])
+	}
+	if !strings.Contains(execs[1].query, "capsuleDeliveries SET status = 'failed'") || execs[1].args[2] != int64(9) {
+		t.Errorf("delivery wasn't marked as failed: %+v", execs[1])
+	}
+}
+
+func TestCompleteJob(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	deliveryId := uint(9)
+	job := types.OutboxJob{ID: 5, Kind: KindMail, Attempts: 2, MaxAttempts: 5, DeliveryID: &deliveryId}
+
+	err := NewOutboxStore(db).CompleteJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execs := recording.execs()
+	if len(execs) != 2 {
+		t.Fatalf("expected the job and its delivery to be updated, got %+v", execs)
+	}
+	if !strings.Contains(execs[0].query, "status = 'sent'") || execs[0].args[0] != int64(5) {
+		t.Errorf("job wasn't marked as sent: %+v", execs[0])
+	}
+	if !strings.Contains(execs[1].query, "capsuleDeliveries SET status = 'sent'") || execs[1].args[0] != int64(3) || execs[1].args[1] != int64(9) {
+		t.Errorf("delivery wasn't marked as sent: %+v", execs[1])
+	}
+}
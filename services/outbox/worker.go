@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// number of jobs a worker claims at a time
+const claimBatchSize = 10
+
+// Worker sends queued jobs through the real backends
+type Worker struct {
+	outboxStore types.OutboxStore
+	deviceStore types.DeviceStore
+	mailer      types.Mailer
+	pushSender  types.PushSender
+	workers     int
+	interval    time.Duration
+}
+
+func NewWorker(outboxStore types.OutboxStore, deviceStore types.DeviceStore, mailer types.Mailer, pushSender types.PushSender) *Worker {
+	return &Worker{
+		outboxStore: outboxStore,
+		deviceStore: deviceStore,
+		mailer:      mailer,
+		pushSender:  pushSender,
+		workers:     int(config.Envs.OutboxWorkers),
+		interval:    time.Second * time.Duration(config.Envs.OutboxPollIntervalInSeconds),
+	}
+}
+
+// Start runs the worker goroutines until the context is cancelled
+func (worker *Worker) Start(ctx context.Context) {
+	if worker.workers <= 0 || worker.interval <= 0 {
+		log.Println("Outbox worker is disabled")
+		return
+	}
+
+	for i := 0; i < worker.workers; i++ {
+		workerId, err := newWorkerId()
+		if err != nil {
+			log.Printf("outbox: error creating worker id: %v", err)
+			return
+		}
+
+		go func() {
+			for {
+				// keep going while there's a backlog, otherwise wait for the next poll
+				if worker.processBatch(workerId) < claimBatchSize {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(worker.interval):
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// newWorkerId identifies a worker across every running instance
+func newWorkerId() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	workerId := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+	if len(workerId) > 64 {
+		workerId = workerId[len(workerId)-64:]
+	}
+	return workerId, nil
+}
+
+func (worker *Worker) processBatch(workerId string) int {
+	jobs, err := worker.outboxStore.ClaimJobs(workerId, claimBatchSize)
+	if err != nil {
+		log.Printf("outbox: error claiming jobs: %v", err)
+		return 0
+	}
+
+	for _, job := range jobs {
+		sendErr := worker.send(job)
+		if sendErr == nil {
+			err = worker.outboxStore.CompleteJob(job)
+		} else {
+			log.Printf("outbox: error sending job %d (attempt %d of %d): %v", job.ID, job.Attempts+1, job.MaxAttempts, sendErr)
+			err = worker.outboxStore.FailJob(job, sendErr)
+		}
+		if err != nil {
+			log.Printf("outbox: error recording result of job %d: %v", job.ID, err)
+		}
+	}
+
+	return len(jobs)
+}
+
+func (worker *Worker) send(job types.OutboxJob) error {
+	switch job.Kind {
+	case KindMail:
+		var mail types.Mail
+		if err := json.Unmarshal(job.Payload, &mail); err != nil {
+			return err
+		}
+		return worker.mailer.Send(mail)
+	case KindPush:
+		var payload pushPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+		return worker.sendPush(payload)
+	default:
+		return fmt.Errorf("unknown outbox job kind %s", job.Kind)
+	}
+}
+
+func (worker *Worker) sendPush(payload pushPayload) error {
+	device, err := worker.deviceStore.GetDeviceById(payload.DeviceID)
+	if err != nil {
+		// the device was unregistered after the push was queued, nothing left to do
+		log.Printf("outbox: skipping push to device %d: %v", payload.DeviceID, err)
+		return nil
+	}
+
+	err = worker.pushSender.Send(*device, payload.Push)
+	if errors.Is(err, push.ErrInvalidToken) {
+		// retrying won't help, so prune the device instead of failing the job
+		log.Printf("outbox: removing device %d: %v", device.ID, err)
+		return worker.deviceStore.DeleteDevice(device.ID)
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TenacityLabs/retrospect-backend/services/push"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type failingMailer struct{}
+
+func (failingMailer) Send(mail types.Mail) error {
+	return errors.New("smtp unavailable")
+}
+
+type testDeviceStore struct {
+	mu      sync.Mutex
+	devices map[uint]types.Device
+	deleted []uint
+}
+
+func (store *testDeviceStore) GetUserDevices(userId uint) ([]types.Device, error) {
+	return nil, errors.New("not used by the worker")
+}
+
+func (store *testDeviceStore) GetDeviceById(deviceId uint) (*types.Device, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	device, ok := store.devices[deviceId]
+	if !ok {
+		return nil, fmt.Errorf("device not found")
+	}
+	return &device, nil
+}
+
+func (store *testDeviceStore) RegisterDevice(userId uint, platform string, token string) error {
+	return errors.New("not used by the worker")
+}
+
+func (store *testDeviceStore) DeleteUserDevice(userId uint, token string) error {
+	return errors.New("not used by the worker")
+}
+
+func (store *testDeviceStore) DeleteDevice(deviceId uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.devices, deviceId)
+	store.deleted = append(store.deleted, deviceId)
+	return nil
+}
+
+// jobResults maps each job id to the status the worker recorded for it
+func jobResults(recording *recordingDB) map[int64]string {
+	results := make(map[int64]string)
+	for _, exec := range recording.execs() {
+		// the claim itself isn't a result
+		if !strings.HasPrefix(strings.TrimSpace(exec.query), "UPDATE outbox") || strings.Contains(exec.query, "SET status = 'processing'") {
+			continue
+		}
+		jobId := exec.args[len(exec.args)-1].(int64)
+		for _, status := range []string{"sent", "pending", "dead"} {
+			if strings.Contains(exec.query, "status = '"+status+"'") {
+				results[jobId] = status
+			}
+		}
+	}
+	return results
+}
+
+func TestProcessBatch(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	recording.rows = [][]driver.Value{
+		jobRow(1, KindPush, `{"deviceId":1,"push":{"title":"Your capsule is ready"}}`, nil, 0, 5),
+		jobRow(2, KindPush, `{"deviceId":2,"push":{"title":"Your capsule is ready"}}`, nil, 0, 5),
+		jobRow(3, KindPush, `{"deviceId":3,"push":{"title":"Your capsule is ready"}}`, nil, 0, 5),
+		jobRow(4, "carrier-pigeon", `{}`, nil, 0, 5),
+	}
+	deviceStore := &testDeviceStore{devices: map[uint]types.Device{
+		1: {ID: 1, Platform: "ios", Token: "device-token"},
+		2: {ID: 2, Platform: "android", Token: "invalid-token"},
+		// device 3 was unregistered after its push was queued
+	}}
+	pushSender := push.NewFakePushSender()
+	worker := NewWorker(NewOutboxStore(db), deviceStore, failingMailer{}, pushSender)
+
+	if claimed := worker.processBatch("worker-1"); claimed != 4 {
+		t.Fatalf("processBatch claimed %d jobs, want 4", claimed)
+	}
+
+	sent := pushSender.Sent()
+	if len(sent) != 1 || sent[0].Device.ID != 1 || sent[0].Push.Title != "Your capsule is ready" {
+		t.Errorf("sent pushes = %+v", sent)
+	}
+	// a rejected token can't be fixed by retrying, the device goes instead
+	if len(deviceStore.deleted) != 1 || deviceStore.deleted[0] != 2 {
+		t.Errorf("deleted devices = %v, want [2]", deviceStore.deleted)
+	}
+
+	want := map[int64]string{1: "sent", 2: "sent", 3: "sent", 4: "pending"}
+	results := jobResults(recording)
+	for jobId, status := range want {
+		if results[jobId] != status {
+			t.Errorf("job %d is %q, want %q", jobId, results[jobId], status)
+		}
+	}
+}
+
+func TestProcessBatchDeadLettersFailingMail(t *testing.T) {
+	db, recording := newRecordingDB(t)
+	recording.rows = [][]driver.Value{
+		jobRow(1, KindMail, `{"to":["ana@retrospect.test"],"subject":"Hi"}`, nil, 0, 3),
+		jobRow(2, KindMail, `{"to":["ben@retrospect.test"],"subject":"Hi"}`, nil, 2, 3),
+	}
+	worker := NewWorker(NewOutboxStore(db), &testDeviceStore{}, failingMailer{}, push.NewFakePushSender())
+
+	worker.processBatch("worker-1")
+
+	// the first failure is retried later, running out of attempts dead letters the job
+	want := map[int64]string{1: "pending", 2: "dead"}
+	results := jobResults(recording)
+	for jobId, status := range want {
+		if results[jobId] != status {
+			t.Errorf("job %d is %q, want %q", jobId, results[jobId], status)
+		}
+	}
+}
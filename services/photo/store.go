@@ -35,8 +35,11 @@ func scanRowIntoPhoto(row *sql.Rows) (*types.Photo, error) {
 	return photo, nil
 }
 
-func (photoStore *PhotoStore) GetPhotos(capsuleID uint) ([]types.Photo, error) {
-	rows, err := photoStore.db.Query("SELECT * FROM photos WHERE capsuleId = ?", capsuleID)
+func (photoStore *PhotoStore) GetPhotos(capsuleID uint, userID uint) ([]types.Photo, error) {
+	rows, err := photoStore.db.Query(
+		"SELECT p.* FROM photos p JOIN capsules c ON p.capsuleId = c.id WHERE p.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR p.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
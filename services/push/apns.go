@@ -0,0 +1,143 @@
+package push
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+)
+
+// apple rejects provider tokens older than an hour, and throttles ones refreshed more than every 20 minutes
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNSPushSender sends to ios devices over the apns http/2 api, authenticating with a .p8 signing key
+type APNSPushSender struct {
+	client *http.Client
+	host   string
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+
+	mu            sync.Mutex
+	token         string
+	tokenIssuedAt time.Time
+}
+
+func NewAPNSPushSender(keyPath string, keyID string, teamID string, topic string, production bool) (*APNSPushSender, error) {
+	keyBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	host := apnsSandboxHost
+	if production {
+		host = apnsProductionHost
+	}
+
+	return &APNSPushSender{
+		client: &http.Client{Timeout: 10 * time.Second},
+		host:   host,
+		key:    key,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+	}, nil
+}
+
+// providerToken reuses the signed token until it's close to expiring
+func (sender *APNSPushSender) providerToken() (string, error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	if sender.token != "" && time.Since(sender.tokenIssuedAt) < apnsTokenLifetime {
+		return sender.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": sender.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = sender.keyID
+
+	signed, err := token.SignedString(sender.key)
+	if err != nil {
+		return "", err
+	}
+	sender.token = signed
+	sender.tokenIssuedAt = now
+	return signed, nil
+}
+
+func (sender *APNSPushSender) Send(device types.Device, push types.Push) error {
+	// custom data sits next to the aps dictionary
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": push.Title, "body": push.Body},
+			"sound": "default",
+		},
+	}
+	for key, value := range push.Data {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	providerToken, err := sender.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sender.host+"/3/device/"+device.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", sender.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("content-type", "application/json")
+
+	res, err := sender.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(res.Body).Decode(&apnsErr)
+
+	switch {
+	case res.StatusCode == http.StatusGone,
+		apnsErr.Reason == "BadDeviceToken",
+		apnsErr.Reason == "Unregistered",
+		apnsErr.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: apns %s", ErrInvalidToken, apnsErr.Reason)
+	default:
+		return fmt.Errorf("apns returned %d: %s", res.StatusCode, apnsErr.Reason)
+	}
+}
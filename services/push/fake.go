@@ -0,0 +1,46 @@
+package push
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+type SentPush struct {
+	Device types.Device
+	Push   types.Push
+}
+
+// FakePushSender records pushes instead of sending them, for tests and local development
+// tokens starting with "invalid" are rejected the way a provider rejects an unregistered device
+type FakePushSender struct {
+	mu   sync.Mutex
+	sent []SentPush
+}
+
+func NewFakePushSender() *FakePushSender {
+	return &FakePushSender{}
+}
+
+func (sender *FakePushSender) Send(device types.Device, push types.Push) error {
+	if strings.HasPrefix(device.Token, "invalid") {
+		return ErrInvalidToken
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sender.sent = append(sender.sent, SentPush{Device: device, Push: push})
+	return nil
+}
+
+// Sent returns every push recorded so far
+func (sender *FakePushSender) Sent() []SentPush {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	sent := make([]SentPush, len(sender.sent))
+	copy(sent, sender.sent)
+	return sent
+}
@@ -0,0 +1,91 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMPushSender sends to android devices over the fcm http v1 api, authenticating as a service account
+type FCMPushSender struct {
+	client    *http.Client
+	projectID string
+}
+
+func NewFCMPushSender(credentialsPath string) (*FCMPushSender, error) {
+	credentialsJSON, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := google.CredentialsFromJSON(context.Background(), credentialsJSON, fcmScope)
+	if err != nil {
+		return nil, err
+	}
+	if credentials.ProjectID == "" {
+		return nil, fmt.Errorf("fcm credentials are missing a project id")
+	}
+
+	// the oauth2 transport fetches and refreshes the access token on its own
+	client := oauth2.NewClient(context.Background(), credentials.TokenSource)
+	client.Timeout = 10 * time.Second
+
+	return &FCMPushSender{
+		client:    client,
+		projectID: credentials.ProjectID,
+	}, nil
+}
+
+func (sender *FCMPushSender) Send(device types.Device, push types.Push) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        device.Token,
+			"notification": map[string]string{"title": push.Title, "body": push.Body},
+			"data":         push.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", sender.projectID)
+	res, err := sender.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var fcmErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(res.Body).Decode(&fcmErr)
+
+	if fcmErr.Error.Status == "NOT_FOUND" {
+		return fmt.Errorf("%w: fcm %s", ErrInvalidToken, fcmErr.Error.Message)
+	}
+	for _, detail := range fcmErr.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return fmt.Errorf("%w: fcm %s", ErrInvalidToken, fcmErr.Error.Message)
+		}
+	}
+	return fmt.Errorf("fcm returned %d: %s", res.StatusCode, fcmErr.Error.Message)
+}
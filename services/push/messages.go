@@ -0,0 +1,103 @@
+package push
+
+import (
+	"fmt"
+
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// capsuleData tells the app which screen to open, the event is also checked against the user's preferences
+func capsuleData(event string, capsuleId uint) map[string]string {
+	return map[string]string{
+		"event":     event,
+		"capsuleId": fmt.Sprint(capsuleId),
+	}
+}
+
+func CapsuleReady(capsuleId uint, capsuleName string) types.Push {
+	return types.Push{
+		Title: capsuleName + " is ready to open!",
+		Body:  "Open it to see what everyone added.",
+		Data:  capsuleData(types.NotificationEventCapsuleReady, capsuleId),
+	}
+}
+
+func CapsuleSealed(capsuleId uint, capsuleName string, daysLeft int) types.Push {
+	unit := "days"
+	if daysLeft == 1 {
+		unit = "day"
+	}
+	return types.Push{
+		Title: capsuleName + " has been sealed",
+		Body:  fmt.Sprintf("The deadline to add to it has passed, it opens in %d %s.", daysLeft, unit),
+		Data:  capsuleData(types.NotificationEventCapsuleSealed, capsuleId),
+	}
+}
+
+func MemberSealed(capsuleId uint, capsuleName string, memberName string) types.Push {
+	return types.Push{
+		Title: memberName + " sealed " + capsuleName,
+		Body:  memberName + " has finished adding to the capsule.",
+		Data:  capsuleData(types.NotificationEventMemberSealed, capsuleId),
+	}
+}
+
+func MemberJoined(capsuleId uint, capsuleName string, memberName string) types.Push {
+	return types.Push{
+		Title: memberName + " joined " + capsuleName,
+		Body:  memberName + " can now add to the capsule.",
+		Data:  capsuleData(types.NotificationEventMemberJoined, capsuleId),
+	}
+}
+
+func CapsuleInvite(capsuleId uint, capsuleName string, inviterName string) types.Push {
+	return types.Push{
+		Title: inviterName + " invited you to " + capsuleName,
+		Body:  "Accept the invite to start adding to the capsule.",
+		Data:  capsuleData(types.NotificationEventCapsuleInvite, capsuleId),
+	}
+}
+
+func CapsuleCountdown(capsuleId uint, capsuleName string, daysLeft int) types.Push {
+	unit := "days"
+	if daysLeft == 1 {
+		unit = "day"
+	}
+	return types.Push{
+		Title: fmt.Sprintf("%s opens in %d %s", capsuleName, daysLeft, unit),
+		Body:  "Not long to go now!",
+		Data:  capsuleData(types.NotificationEventCountdown, capsuleId),
+	}
+}
+
+func CapsuleAnniversary(capsuleId uint, capsuleName string) types.Push {
+	return types.Push{
+		Title: "A year since you opened " + capsuleName,
+		Body:  "Take another look back.",
+		Data:  capsuleData(types.NotificationEventAnniversary, capsuleId),
+	}
+}
+
+func SealNudge(capsuleId uint, capsuleName string, ownerName string) types.Push {
+	return types.Push{
+		Title: ownerName + " is waiting on you",
+		Body:  "Finish adding to " + capsuleName + " and seal it.",
+		Data:  capsuleData(types.NotificationEventSealNudge, capsuleId),
+	}
+}
+
+func LetterUnlocked(capsuleId uint, capsuleName string) types.Push {
+	return types.Push{
+		Title: "A letter in " + capsuleName + " is ready for you",
+		Body:  "Open the capsule to read it.",
+		Data:  capsuleData(types.NotificationEventLetterUnlock, capsuleId),
+	}
+}
+
+func GiftReady(capsuleId uint, capsuleName string, senderName string) types.Push {
+	return types.Push{
+		Title: senderName + " made you a time capsule",
+		Body:  capsuleName + " is open, see what's inside.",
+		Data:  capsuleData(types.NotificationEventGiftReady, capsuleId),
+	}
+}
@@ -0,0 +1,54 @@
+package push
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// ErrInvalidToken is returned when the provider says a device token will never work again, so it should be pruned
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// NewPushSender picks the push backend from the environment
+func NewPushSender() types.PushSender {
+	switch config.Envs.PushBackend {
+	case "fake":
+		return NewFakePushSender()
+	case "live":
+		sender := &PlatformPushSender{senders: make(map[string]types.PushSender)}
+		if config.Envs.APNSKeyPath != "" {
+			apns, err := NewAPNSPushSender(config.Envs.APNSKeyPath, config.Envs.APNSKeyID, config.Envs.APNSTeamID, config.Envs.APNSTopic, config.Envs.APNSProduction)
+			if err != nil {
+				log.Fatalf("Failed to create APNs push sender: %v", err)
+			}
+			sender.senders["ios"] = apns
+		}
+		if config.Envs.FCMCredentialsPath != "" {
+			fcm, err := NewFCMPushSender(config.Envs.FCMCredentialsPath)
+			if err != nil {
+				log.Fatalf("Failed to create FCM push sender: %v", err)
+			}
+			sender.senders["android"] = fcm
+		}
+		return sender
+	default:
+		log.Fatalf("Unknown push backend: %s", config.Envs.PushBackend)
+		return nil
+	}
+}
+
+// PlatformPushSender hands each push to the provider for the device's platform
+type PlatformPushSender struct {
+	senders map[string]types.PushSender
+}
+
+func (sender *PlatformPushSender) Send(device types.Device, push types.Push) error {
+	platformSender, ok := sender.senders[device.Platform]
+	if !ok {
+		return fmt.Errorf("push to %s devices isn't configured", device.Platform)
+	}
+	return platformSender.Send(device, push)
+}
@@ -49,10 +49,10 @@ func (handler *Handler) handleCreateQuestionAnswer(w http.ResponseWriter, r *htt
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	// check if user is member of capsule
-	_, err = handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("could not find capsule with id %d", payload.CapsuleID))
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
 
@@ -82,10 +82,10 @@ func (handler *Handler) handleUpdateQuestionAnswer(w http.ResponseWriter, r *htt
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	// check if user is member of capsule
-	_, err = handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("could not find capsule with id %d", payload.CapsuleID))
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
 
@@ -115,6 +115,13 @@ func (handler *Handler) handleDeleteQuestionAnswer(w http.ResponseWriter, r *htt
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
 	err = handler.questionAnswerStore.DeleteQuestionAnswer(userID, payload.CapsuleID, payload.QuestionAnswerID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -34,8 +34,11 @@ func scanRowIntoQuestionAnswer(row *sql.Rows) (*types.QuestionAnswer, error) {
 	return questionAnswer, nil
 }
 
-func (questionAnswerStore *QuestionAnswerStore) GetQuestionAnswers(capsuleID uint) ([]types.QuestionAnswer, error) {
-	rows, err := questionAnswerStore.db.Query("SELECT * FROM questionAnswers WHERE capsuleId = ?", capsuleID)
+func (questionAnswerStore *QuestionAnswerStore) GetQuestionAnswers(capsuleID uint, userID uint) ([]types.QuestionAnswer, error) {
+	rows, err := questionAnswerStore.db.Query(
+		"SELECT q.* FROM questionAnswers q JOIN capsules c ON q.capsuleId = c.id WHERE q.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR q.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/TenacityLabs/retrospect-backend/config"
+	"github.com/TenacityLabs/retrospect-backend/types"
+)
+
+// name of the mysql lock held by whichever instance is running the scheduled tasks
+const lockName = "retrospect_scheduler"
+
+type Scheduler struct {
+	db           *sql.DB
+	capsuleStore types.CapsuleStore
+	giftStore    types.GiftStore
+	interval     time.Duration
+	autoOpen     bool
+	autoNudge    bool
+}
+
+func NewScheduler(db *sql.DB, capsuleStore types.CapsuleStore, giftStore types.GiftStore) *Scheduler {
+	return &Scheduler{
+		db:           db,
+		capsuleStore: capsuleStore,
+		giftStore:    giftStore,
+		interval:     time.Second * time.Duration(config.Envs.SchedulerIntervalInSeconds),
+		autoOpen:     config.Envs.SchedulerAutoOpen,
+		autoNudge:    config.Envs.AutoNudgeAfterDays > 0,
+	}
+}
+
+// Start runs the scheduled tasks every interval until the context is cancelled
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	if scheduler.interval <= 0 {
+		log.Println("Scheduler is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(scheduler.interval)
+		defer ticker.Stop()
+
+		for {
+			scheduler.tick(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// tick runs the tasks if this instance wins the lock, so multiple instances don't double send
+func (scheduler *Scheduler) tick(ctx context.Context) {
+	// locks belong to a connection, so hold onto one for the whole tick
+	conn, err := scheduler.db.Conn(ctx)
+	if err != nil {
+		log.Printf("scheduler: error getting db connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", lockName).Scan(&acquired)
+	if err != nil {
+		log.Printf("scheduler: error acquiring lock: %v", err)
+		return
+	}
+	if acquired.Int64 != 1 {
+		return
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName)
+
+	scheduler.run()
+}
+
+func (scheduler *Scheduler) run() {
+	if scheduler.autoNudge {
+		nudged, err := scheduler.capsuleStore.NudgeStaleCapsules()
+		if err != nil {
+			log.Printf("scheduler: error nudging members: %v", err)
+		}
+		if nudged > 0 {
+			log.Printf("scheduler: nudged %d members", nudged)
+		}
+	}
+
+	// sealed before the reminders so everyone hears about it this run
+	sealed, err := scheduler.capsuleStore.SealExpiredCapsules()
+	if err != nil {
+		log.Printf("scheduler: error sealing capsules past their deadline: %v", err)
+	}
+	if sealed > 0 {
+		log.Printf("scheduler: sealed %d capsules past their deadline", sealed)
+	}
+
+	err = scheduler.capsuleStore.SendReminderMail()
+	if err != nil {
+		// don't open capsules until every member's reminder has been queued
+		log.Printf("scheduler: error sending reminder mail: %v", err)
+	} else if scheduler.autoOpen {
+		opened, err := scheduler.capsuleStore.OpenDueCapsules()
+		if err != nil {
+			log.Printf("scheduler: error opening capsules: %v", err)
+		}
+		if opened > 0 {
+			log.Printf("scheduler: opened %d capsules", opened)
+		}
+	}
+
+	// the capsules opened by hand are normally renewed as they're opened, this catches up on any that failed
+	renewed, err := scheduler.capsuleStore.RenewRecurringCapsules()
+	if err != nil {
+		log.Printf("scheduler: error creating the next editions of recurring capsules: %v", err)
+	}
+	if renewed > 0 {
+		log.Printf("scheduler: created the next edition of %d recurring capsules", renewed)
+	}
+
+	// capsules opened by hand get their gifts delivered here too
+	delivered, err := scheduler.giftStore.DeliverGifts()
+	if err != nil {
+		log.Printf("scheduler: error delivering gifts: %v", err)
+	}
+	if delivered > 0 {
+		log.Printf("scheduler: delivered %d gifts", delivered)
+	}
+}
@@ -37,8 +37,11 @@ func scanRowIntoSong(row *sql.Rows) (*types.Song, error) {
 	return song, nil
 }
 
-func (songStore *SongStore) GetSongs(capsuleID uint) ([]types.Song, error) {
-	rows, err := songStore.db.Query("SELECT * FROM songs WHERE capsuleId = ?", capsuleID)
+func (songStore *SongStore) GetSongs(capsuleID uint, userID uint) ([]types.Song, error) {
+	rows, err := songStore.db.Query(
+		"SELECT s.* FROM songs s JOIN capsules c ON s.capsuleId = c.id WHERE s.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR s.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
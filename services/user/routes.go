@@ -2,11 +2,16 @@ package user
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/TenacityLabs/retrospect-backend/config"
 	"github.com/TenacityLabs/retrospect-backend/services/auth"
+	"github.com/TenacityLabs/retrospect-backend/services/gift"
+	"github.com/TenacityLabs/retrospect-backend/services/invite"
+	"github.com/TenacityLabs/retrospect-backend/services/mail"
 	"github.com/TenacityLabs/retrospect-backend/types"
 	"github.com/TenacityLabs/retrospect-backend/utils"
 	"github.com/go-playground/validator/v10"
@@ -15,22 +20,35 @@ import (
 )
 
 type Handler struct {
-	userStore types.UserStore
+	userStore    types.UserStore
+	capsuleStore types.CapsuleStore
+	inviteStore  types.InviteStore
+	giftStore    types.GiftStore
+	fileStore    types.FileStore
+	mailer       types.Mailer
 }
 
-func NewHandler(userStore types.UserStore) *Handler {
+func NewHandler(userStore types.UserStore, capsuleStore types.CapsuleStore, inviteStore types.InviteStore, giftStore types.GiftStore, fileStore types.FileStore, mailer types.Mailer) *Handler {
 	return &Handler{
-		userStore: userStore,
+		userStore:    userStore,
+		capsuleStore: capsuleStore,
+		inviteStore:  inviteStore,
+		giftStore:    giftStore,
+		fileStore:    fileStore,
+		mailer:       mailer,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/user/login", h.handleLogin).Methods("POST")
 	router.HandleFunc("/user/register", h.handleRegister).Methods("POST")
+	router.HandleFunc("/user/forgot-password", h.handleForgotPassword).Methods("POST")
+	router.HandleFunc("/user/reset-password", h.handleResetPassword).Methods("POST")
 	router.HandleFunc("/user", auth.WithJWTAuth(h.handleGetUser, h.userStore)).Methods("GET")
 	router.HandleFunc("/user/name/{userId}", auth.WithJWTAuth(h.handleGetUserNameById, h.userStore)).Methods("GET")
 	router.HandleFunc("/user/delete", auth.WithJWTAuth(h.handleDeleteUser, h.userStore)).Methods("POST")
 	router.HandleFunc("/user/update", auth.WithJWTAuth(h.handleUpdateUser, h.userStore)).Methods("POST")
+	router.HandleFunc("/user/timezone", auth.WithJWTAuth(h.handleUpdateUserTimezone, h.userStore)).Methods("POST")
 	router.HandleFunc("/user/update-password", auth.WithJWTAuth(h.handleUpdateUserPassword, h.userStore)).Methods("POST")
 	router.HandleFunc("/user/process-contacts", auth.WithJWTAuth(h.handleProcessContacts, h.userStore)).Methods("POST")
 	router.HandleFunc("/user/add-referral", auth.WithJWTAuth(h.handleAddReferral, h.userStore)).Methods("POST")
@@ -105,12 +123,28 @@ func (handler *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create user
-	err = handler.userStore.CreateUser(payload.Name, payload.Email, payload.Phone, hashedPassword)
+	err = handler.userStore.CreateUser(payload.Name, payload.Email, utils.NormalizePhone(payload.Phone), hashedPassword)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	// join any capsules the user was invited to before registering
+	user, err := handler.userStore.GetUserByEmail(payload.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	invite.ClaimPendingInvites(handler.inviteStore, handler.capsuleStore, user)
+
+	// the gift link they signed up from, failing to claim it shouldn't fail the signup
+	if payload.GiftToken != "" {
+		err = gift.ClaimGiftToken(handler.giftStore, payload.GiftToken, user.ID)
+		if err != nil {
+			log.Printf("error claiming gift for user %d: %v", user.ID, err)
+		}
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, nil)
 }
 
@@ -151,7 +185,21 @@ func (handler *Handler) handleGetUserNameById(w http.ResponseWriter, r *http.Req
 func (handler *Handler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	err := handler.userStore.DeleteUser(userID)
+	// hand off or delete the user's capsules first, nothing may point at the user once they're gone
+	objectNames, err := handler.capsuleStore.RemoveUserFromCapsules(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, objectName := range objectNames {
+		err = handler.fileStore.DeleteFile(objectName)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	err = handler.userStore.DeleteUser(userID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -170,7 +218,7 @@ func (handler *Handler) handleUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = handler.userStore.UpdateUser(userID, payload.Name, payload.Email, payload.Phone)
+	err = handler.userStore.UpdateUser(userID, payload.Name, payload.Email, utils.NormalizePhone(payload.Phone))
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -179,6 +227,31 @@ func (handler *Handler) handleUpdateUser(w http.ResponseWriter, r *http.Request)
 	utils.WriteJSON(w, http.StatusCreated, nil)
 }
 
+func (handler *Handler) handleUpdateUserTimezone(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIdFromContext(r.Context())
+
+	var payload types.UpdateUserTimezonePayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	err = handler.userStore.UpdateUserTimezone(userID, payload.Timezone)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
 func (handler *Handler) handleUpdateUserPassword(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIdFromContext(r.Context())
 
@@ -198,6 +271,79 @@ func (handler *Handler) handleUpdateUserPassword(w http.ResponseWriter, r *http.
 	utils.WriteJSON(w, http.StatusCreated, nil)
 }
 
+func (handler *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ForgotPasswordPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	// respond the same way whether or not the account exists, so emails can't be probed
+	user, err := handler.userStore.GetUserByEmail(payload.Email)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusOK, nil)
+		return
+	}
+
+	data := mail.TemplateData{
+		RecipientName: user.Name,
+		Link:          config.Envs.PublicHost + "/reset-password?token=" + url.QueryEscape(auth.CreatePasswordResetToken(user)),
+	}
+	resetMail, err := mail.RenderMail([]string{user.Email}, mail.TemplatePasswordReset, data)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = handler.mailer.Send(resetMail)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+func (handler *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+	err := utils.ParseJSON(r, &payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	user, err := auth.VerifyPasswordResetToken(payload.Token, handler.userStore)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = handler.userStore.UpdateUserPassword(user.ID, hashedPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
 func (handler *Handler) handleProcessContacts(w http.ResponseWriter, r *http.Request) {
 	var payload types.ProcessContactsPayload
 	err := utils.ParseJSON(r, &payload)
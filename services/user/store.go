@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/TenacityLabs/retrospect-backend/types"
+	"github.com/TenacityLabs/retrospect-backend/utils"
 )
 
 type UserStore struct {
@@ -29,6 +30,7 @@ func scanRowIntoUser(row *sql.Rows) (*types.User, error) {
 		&user.Password,
 		&user.ReferralCount,
 		&user.CreatedAt,
+		&user.Timezone,
 	)
 	if err != nil {
 		return nil, err
@@ -109,6 +111,27 @@ func (userStore *UserStore) GetUserByEmail(email string) (*types.User, error) {
 	return user, nil
 }
 
+func (userStore *UserStore) GetUserByPhone(phone string) (*types.User, error) {
+	rows, err := userStore.db.Query("SELECT * FROM users WHERE phone = ?", phone)
+	if err != nil {
+		return nil, err
+	}
+
+	user := new(types.User)
+	for rows.Next() {
+		user, err = scanRowIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.ID == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return user, nil
+}
+
 func (userStore *UserStore) GetUserById(userId uint) (*types.User, error) {
 	rows, err := userStore.db.Query("SELECT * FROM users WHERE id = ?", userId)
 	if err != nil {
@@ -155,14 +178,39 @@ func (userStore *UserStore) CreateUser(name string, email string, phone string,
 	return nil
 }
 
-// delete user leaves memory leaks (eg. capsules where the owner is deleted)
-// but this feature is only for testing, so it's fine
+// DeleteUser deletes the user along with their devices, preferences, templates, requests and invites they sent,
+// the user should already have been taken out of their capsules with CapsuleStore.RemoveUserFromCapsules
 func (userStore *UserStore) DeleteUser(userId uint) error {
-	_, err := userStore.db.Exec("DELETE FROM users WHERE id = ?", userId)
+	tx, err := userStore.db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	deleteUserQueries := []string{
+		"DELETE FROM capsuleMembers WHERE userId = ?",
+		"DELETE FROM devices WHERE userId = ?",
+		"DELETE FROM notificationPreferences WHERE userId = ?",
+		"DELETE FROM joinRequests WHERE userId = ?",
+		"DELETE FROM invites WHERE inviterId = ?",
+		"DELETE o FROM outbox o JOIN capsuleDeliveries d ON o.deliveryId = d.id WHERE d.userId = ?",
+		"DELETE FROM capsuleDeliveries WHERE userId = ?",
+		"DELETE FROM giftRecipients WHERE senderId = ?",
+		// gifts addressed to the user can still be claimed through their email
+		"UPDATE giftRecipients SET userId = NULL WHERE userId = ?",
+		"UPDATE capsules c JOIN capsuleTemplates t ON c.templateId = t.id SET c.templateId = NULL WHERE t.userId = ?",
+		"DELETE p FROM capsuleTemplatePrompts p JOIN capsuleTemplates t ON p.templateId = t.id WHERE t.userId = ?",
+		"DELETE FROM capsuleTemplates WHERE userId = ?",
+		"DELETE FROM users WHERE id = ?",
+	}
+	for _, query := range deleteUserQueries {
+		_, err = tx.Exec(query, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (userStore *UserStore) UpdateUser(userId uint, name string, email string, phone string) error {
@@ -181,6 +229,11 @@ func (userStore *UserStore) UpdateUserPassword(userId uint, password string) err
 	return nil
 }
 
+func (userStore *UserStore) UpdateUserTimezone(userId uint, timezone string) error {
+	_, err := userStore.db.Exec("UPDATE users SET timezone = ? WHERE id = ?", timezone, userId)
+	return err
+}
+
 func generatePlaceholders(n int) string {
 	if n <= 0 {
 		return ""
@@ -197,6 +250,11 @@ func convertToInterfaceSlice(stringSlice []string) []interface{} {
 }
 
 func (userStore *UserStore) ProcessContacts(contacts []types.Contact) ([]types.Contact, []types.Contact, []types.Contact, error) {
+	// contacts come formatted however the phone stores them, match on the bare number
+	for i := range contacts {
+		contacts[i].Phone = utils.NormalizePhone(contacts[i].Phone)
+	}
+
 	// first strip all contacts that are already users
 	var allPhones []string
 	for _, contact := range contacts {
@@ -49,10 +49,10 @@ func (handler *Handler) handleCreateWriting(w http.ResponseWriter, r *http.Reque
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
-	// check if user is member of capsule
-	_, err = handler.capsuleStore.GetCapsuleById(userID, payload.CapsuleID)
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
 	if err != nil {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("could not find capsule with id %d", payload.CapsuleID))
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
 		return
 	}
 
@@ -82,6 +82,13 @@ func (handler *Handler) handleUpdateWriting(w http.ResponseWriter, r *http.Reque
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
 	err = handler.writingStore.UpdateWriting(userID, payload.CapsuleID, payload.WritingID, payload.Writing)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -108,6 +115,13 @@ func (handler *Handler) handleDeleteWriting(w http.ResponseWriter, r *http.Reque
 
 	userID := auth.GetUserIdFromContext(r.Context())
 
+	// check if user can change the capsule contents
+	_, err = handler.capsuleStore.AuthorizeCapsule(userID, payload.CapsuleID, types.CapsulePermissionContribute)
+	if err != nil {
+		utils.WriteError(w, utils.AuthorizeErrorStatus(err), err)
+		return
+	}
+
 	err = handler.writingStore.DeleteWriting(userID, payload.CapsuleID, payload.WritingID)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
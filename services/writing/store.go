@@ -33,8 +33,11 @@ func scanRowIntoWriting(row *sql.Rows) (*types.Writing, error) {
 	return writing, nil
 }
 
-func (writingStore *WritingStore) GetWritings(capsuleID uint) ([]types.Writing, error) {
-	rows, err := writingStore.db.Query("SELECT * FROM writings WHERE capsuleId = ?", capsuleID)
+func (writingStore *WritingStore) GetWritings(capsuleID uint, userID uint) ([]types.Writing, error) {
+	rows, err := writingStore.db.Query(
+		"SELECT w.* FROM writings w JOIN capsules c ON w.capsuleId = c.id WHERE w.capsuleId = ? AND (c.surprise = FALSE OR c.sealed = 'opened' OR w.userId = ?)",
+		capsuleID, userID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"encoding/json"
+	"errors"
 	"mime/multipart"
 	"time"
 )
@@ -17,16 +19,19 @@ type User struct {
 	Password      string    `json:"-"`
 	ReferralCount uint      `json:"referralCount"`
 	CreatedAt     time.Time `json:"createdAt"`
+	Timezone      string    `json:"timezone"`
 }
 
 type UserStore interface {
 	GetUserByEmail(email string) (*User, error)
+	GetUserByPhone(phone string) (*User, error)
 	GetUserById(userId uint) (*User, error)
 	GetUserNameById(userId uint) (string, error)
 	CreateUser(name string, email string, phone string, password string) error
 	DeleteUser(userId uint) error
 	UpdateUser(userId uint, name string, email string, phone string) error
 	UpdateUserPassword(userId uint, password string) error
+	UpdateUserTimezone(userId uint, timezone string) error
 	ProcessContacts([]Contact) ([]Contact, []Contact, []Contact, error)
 	AddReferral(userId uint, phone string) error
 }
@@ -41,6 +46,8 @@ type RegisterUserPayload struct {
 	Email    string `json:"email" validate:"required,email"`
 	Phone    string `json:"phone" validate:"required,min=10,max=10"`
 	Password string `json:"password" validate:"required,min=6,max=130"`
+
+	GiftToken string `json:"giftToken"` // claims the gift the user signed up from
 }
 
 type UpdateUserPayload struct {
@@ -49,10 +56,23 @@ type UpdateUserPayload struct {
 	Phone string `json:"phone" validate:"required,min=10,max=10"`
 }
 
+type UpdateUserTimezonePayload struct {
+	Timezone string `json:"timezone" validate:"required,timezone"`
+}
+
 type UpdateUserPasswordPayload struct {
 	Password string `json:"password" validate:"required,min=6,max=130"`
 }
 
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=130"`
+}
+
 type ProcessContactsPayload struct {
 	Contacts []Contact `json:"contacts" validate:"required,dive"`
 }
@@ -87,6 +107,167 @@ type DeleteFilePayload struct {
 	ObjectName string `json:"objectName" validate:"required"`
 }
 
+// ====================================================================
+// Mail
+// ====================================================================
+
+type Mail struct {
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body"`     // plaintext body
+	HTMLBody string   `json:"htmlBody"` // optional html alternative to the plaintext body
+
+	UnsubscribeURL string `json:"unsubscribeUrl"` // sent as the List-Unsubscribe header when set
+}
+
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// ====================================================================
+// Outbox
+// ====================================================================
+
+type OutboxJob struct {
+	ID          uint            `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	DeliveryID  *uint           `json:"deliveryId"`
+	Status      string          `json:"status"`
+	Attempts    uint            `json:"attempts"`
+	MaxAttempts uint            `json:"maxAttempts"`
+	LastError   *string         `json:"lastError"`
+	RunAt       time.Time       `json:"runAt"`
+	ClaimedBy   *string         `json:"claimedBy"`
+	ClaimedAt   *time.Time      `json:"claimedAt"`
+	SentAt      *time.Time      `json:"sentAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	JobKey      *string         `json:"jobKey"`
+}
+
+type OutboxStore interface {
+	EnqueueMail(mail Mail, deliveryId *uint, jobKey string) (uint, error)
+	EnqueuePush(userId uint, push Push, jobKey string) error
+	ClaimJobs(workerId string, limit int) ([]OutboxJob, error)
+	CompleteJob(job OutboxJob) error
+	FailJob(job OutboxJob, sendErr error) error
+	GetJobs(status string, limit int) ([]OutboxJob, error)
+	GetJobById(jobId uint) (*OutboxJob, error)
+	ReplayJob(jobId uint) error
+}
+
+type ReplayOutboxJobPayload struct {
+	JobID uint `json:"jobId" validate:"required"`
+}
+
+// ====================================================================
+// Notification preferences
+// ====================================================================
+
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelPush  = "push"
+	NotificationChannelSMS   = "sms"
+)
+
+const (
+	NotificationEventCapsuleReady  = "capsule-ready"
+	NotificationEventCapsuleInvite = "capsule-invite"
+	NotificationEventMemberSealed  = "member-sealed"
+	NotificationEventMemberJoined  = "member-joined"
+	NotificationEventJoinRequest   = "join-request"
+	NotificationEventCountdown     = "capsule-countdown"
+	NotificationEventAnniversary   = "capsule-anniversary"
+	NotificationEventSealNudge     = "seal-nudge"
+	NotificationEventCapsuleSealed = "capsule-sealed"
+	NotificationEventLetterUnlock  = "letter-unlocked"
+	NotificationEventGiftReady     = "gift-ready"
+)
+
+var NotificationChannels = []string{NotificationChannelEmail, NotificationChannelPush, NotificationChannelSMS}
+
+var NotificationEvents = []string{
+	NotificationEventCapsuleReady,
+	NotificationEventCapsuleInvite,
+	NotificationEventMemberSealed,
+	NotificationEventMemberJoined,
+	NotificationEventJoinRequest,
+	NotificationEventCountdown,
+	NotificationEventAnniversary,
+	NotificationEventSealNudge,
+	NotificationEventCapsuleSealed,
+	NotificationEventLetterUnlock,
+	NotificationEventGiftReady,
+}
+
+type NotificationPreference struct {
+	Channel string `json:"channel"`
+	Event   string `json:"event"`
+	Enabled bool   `json:"enabled"`
+}
+
+type NotificationPreferenceStore interface {
+	GetNotificationPreferences(userId uint) ([]NotificationPreference, error)
+	SetNotificationPreference(userId uint, channel string, event string, enabled bool) error
+	IsNotificationEnabled(userId uint, channel string, event string) (bool, error)
+}
+
+type UpdateNotificationPreferencePayload struct {
+	Channel string `json:"channel" validate:"required,oneof=email push sms"`
+	Event   string `json:"event" validate:"required,oneof=capsule-ready capsule-invite member-sealed member-joined join-request capsule-countdown capsule-anniversary seal-nudge capsule-sealed letter-unlocked gift-ready"`
+	Enabled *bool  `json:"enabled" validate:"required"`
+}
+
+type UnsubscribePayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ====================================================================
+// Device
+// ====================================================================
+
+type Device struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"userId"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DeviceStore interface {
+	GetUserDevices(userId uint) ([]Device, error)
+	GetDeviceById(deviceId uint) (*Device, error)
+	RegisterDevice(userId uint, platform string, token string) error
+	DeleteUserDevice(userId uint, token string) error
+	DeleteDevice(deviceId uint) error
+}
+
+type Push struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data"` // passed through to the app, eg. which capsule to open
+}
+
+// PushSender delivers a push to a single device
+type PushSender interface {
+	Send(device Device, push Push) error
+}
+
+// Pusher notifies every device a user has registered
+type Pusher interface {
+	Push(userId uint, push Push) error
+}
+
+type RegisterDevicePayload struct {
+	Platform string `json:"platform" validate:"required,oneof=ios android"`
+	Token    string `json:"token" validate:"required,max=255"`
+}
+
+type UnregisterDevicePayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
 // ====================================================================
 // Capsule
 // ====================================================================
@@ -98,36 +279,131 @@ type Capsule struct {
 	Public         bool      `json:"public"`
 	CapsuleOwnerID uint      `json:"capsuleOwnerId"`
 
-	CapsuleMember1ID uint `json:"capsuleMember1Id"`
-	CapsuleMember2ID uint `json:"capsuleMember2Id"`
-	CapsuleMember3ID uint `json:"capsuleMember3Id"`
-	CapsuleMember4ID uint `json:"capsuleMember4Id"`
-	CapsuleMember5ID uint `json:"capsuleMember5Id"`
+	Vessel      string          `json:"vessel"`
+	Name        string          `json:"name"`
+	DateToOpen  *time.Time      `json:"dateToOpen"`
+	Sealed      string          `json:"sealed"`
+	OpenedAt    *time.Time      `json:"openedAt"`
+	MemberLimit uint            `json:"memberLimit"`
+	Members     []CapsuleMember `json:"members"`
+	Role        string          `json:"role"` // role of the requesting user in the capsule
+
+	ContributionDeadline *time.Time `json:"contributionDeadline"` // sealed for everyone once this passes
+	DeadlineSealedAt     *time.Time `json:"deadlineSealedAt"`     // when the contribution deadline sealed the capsule
+	Timezone             string     `json:"timezone"`             // dates to open are picked and shown in this timezone
+	LocalDateToOpen      string     `json:"localDateToOpen"`      // dateToOpen in the capsule's timezone
+	OpenPolicy           string     `json:"openPolicy"`           // who can open the capsule once it's due
+	OpenQuorum           uint       `json:"openQuorum"`           // votes needed under the quorum policy, 0 for a majority of members
+	Surprise             bool       `json:"surprise"`             // members only see their own contributions until it's opened
+
+	RecurrenceUnit     *string    `json:"recurrenceUnit"`     // nil if the capsule doesn't recur
+	RecurrenceInterval uint       `json:"recurrenceInterval"` // units between editions
+	NamePattern        string     `json:"namePattern"`        // name of the next edition, empty to keep the same name
+	SeriesID           *uint      `json:"seriesId"`           // first edition of the series, nil if it never recurred
+	Edition            uint       `json:"edition"`
+	RenewedAt          *time.Time `json:"renewedAt"` // when the next edition was created
+
+	TemplateID *uint `json:"templateId"` // template the capsule was created from, its prompts are given to members who join
+
+	CodeExpiresAt *time.Time `json:"codeExpiresAt"`
+	CodeMaxUses   *uint      `json:"codeMaxUses"`
+	CodeUses      uint       `json:"codeUses"`
+	CodeRevoked   bool       `json:"codeRevoked"`
+}
+
+type CapsuleMember struct {
+	ID        uint       `json:"id"`
+	CapsuleID uint       `json:"capsuleId"`
+	UserID    uint       `json:"userId"`
+	Role      string     `json:"role"`
+	SealedAt  *time.Time `json:"sealedAt"`
+	JoinedAt  time.Time  `json:"joinedAt"`
+
+	LastNudgedAt *time.Time `json:"lastNudgedAt"`
+	OpenVotedAt  *time.Time `json:"openVotedAt"`
+}
+
+// OutstandingCapsuleMember is a member the owner is still waiting on to seal
+type OutstandingCapsuleMember struct {
+	UserID       uint       `json:"userId"`
+	Name         string     `json:"name"`
+	Email        string     `json:"-"`
+	Role         string     `json:"role"`
+	LastNudgedAt *time.Time `json:"lastNudgedAt"`
+	Nudged       bool       `json:"nudged"`      // whether they were nudged by this request
+	NextNudgeAt  *time.Time `json:"nextNudgeAt"` // when they can be nudged again, nil if they can be now
+}
+
+const (
+	CapsuleRoleOwner       = "owner"
+	CapsuleRoleEditor      = "editor"
+	CapsuleRoleContributor = "contributor"
+	CapsuleRoleViewer      = "viewer"
+)
+
+const (
+	CapsulePermissionView          = "view"
+	CapsulePermissionContribute    = "contribute to"
+	CapsulePermissionRename        = "rename"
+	CapsulePermissionSeal          = "seal"
+	CapsulePermissionOpen          = "open"
+	CapsulePermissionDelete        = "delete"
+	CapsulePermissionManageMembers = "manage members of"
+	CapsulePermissionTransfer      = "transfer ownership of"
+	CapsulePermissionLeave         = "leave"
+	CapsulePermissionSetOpenPolicy = "set who can open"
+	CapsulePermissionSetSurprise   = "change surprise mode of"
+	CapsulePermissionSetRecurrence = "set how often to repeat"
+)
 
-	CapsuleMember1Sealed bool `json:"capsuleMember1Sealed"`
-	CapsuleMember2Sealed bool `json:"capsuleMember2Sealed"`
-	CapsuleMember3Sealed bool `json:"capsuleMember3Sealed"`
-	CapsuleMember4Sealed bool `json:"capsuleMember4Sealed"`
-	CapsuleMember5Sealed bool `json:"capsuleMember5Sealed"`
+const (
+	CapsuleOpenPolicyOwner     = "owner"      // the owner and editors, whoever has the open permission
+	CapsuleOpenPolicyAnyMember = "any-member" // anyone in the capsule
+	CapsuleOpenPolicyQuorum    = "quorum"     // once enough members have voted to open it
+)
 
-	Vessel     string     `json:"vessel"`
-	Name       string     `json:"name"`
-	DateToOpen *time.Time `json:"dateToOpen"`
-	EmailSent  bool       `json:"emailSent"`
-	Sealed     string     `json:"sealed"`
-}
+// errors returned by CapsuleStore.AuthorizeCapsule, permission errors wrap ErrCapsulePermissionDenied
+var (
+	ErrCapsuleNotFound         = errors.New("capsule not found")
+	ErrCapsulePermissionDenied = errors.New("permission denied")
+)
 
 type CapsuleStore interface {
 	GetCapsules(userId uint) ([]Capsule, error)
 	GetCapsuleById(userId uint, capsuleId uint) (Capsule, error)
-	GetCapsuleByIdUnsafe(userId uint, capsuleId uint) (Capsule, error)
-	CreateCapsule(userId uint, vessel string, public bool) (uint, error)
+	AuthorizeCapsule(userId uint, capsuleId uint, permission string) (Capsule, error)
+	CreateCapsule(userId uint, vessel string, public bool, memberLimit uint, surprise bool) (uint, error)
+	CreateCapsuleFromTemplate(userId uint, template CapsuleTemplate, public bool, memberLimit uint, surprise bool) (uint, error)
+	GetCapsuleByCode(code string) (Capsule, error)
+	GetOpenedCapsuleById(capsuleId uint) (Capsule, error)
 	JoinCapsule(userId uint, code string) error
+	UseCapsuleCode(capsuleId uint) error
+	RegenerateCapsuleCode(capsuleId uint) (string, error)
+	UpdateCapsuleCodeSettings(capsuleId uint, expiresAt *time.Time, maxUses *uint, revoked bool) error
+	AddCapsuleMember(capsuleId uint, userId uint) error
 	DeleteCapsule(userId uint, capsuleId uint) ([]string, error)
-	NameCapsule(userId uint, capsuleId uint, name string) error
-	SealCapsule(userId uint, capsuleId uint, dateToOpen time.Time) error
-	MemberSealCapsule(userId uint, capsuleId uint, memberNumber uint) error
-	OpenCapsule(userId uint, capsuleId uint) error
+	NameCapsule(capsuleId uint, name string) error
+	SealCapsule(userId uint, capsuleId uint, dateToOpen time.Time, timezone string) error
+	MemberSealCapsule(userId uint, capsuleId uint) error
+	GetOutstandingCapsuleMembers(capsuleId uint) ([]OutstandingCapsuleMember, error)
+	NudgeCapsuleMembers(capsuleId uint, userId uint) ([]OutstandingCapsuleMember, error)
+	NudgeStaleCapsules() (int64, error)
+	SetContributionDeadline(capsuleId uint, deadline *time.Time, dateToOpen *time.Time, timezone string) error
+	SealExpiredCapsules() (int64, error)
+	SetCapsuleMemberRole(capsuleId uint, userId uint, role string) error
+	RemoveCapsuleMember(capsuleId uint, userId uint) ([]string, error)
+	RemoveUserFromCapsules(userId uint) ([]string, error)
+	TransferCapsuleOwnership(capsuleId uint, ownerId uint, newOwnerId uint) error
+	OpenCapsule(capsuleId uint) error
+	OpenDueCapsules() (int64, error)
+	SetOpenPolicy(capsuleId uint, openPolicy string, openQuorum uint) error
+	SetCapsuleSurprise(capsuleId uint, surprise bool) error
+	GetHiddenContributionCounts(capsuleId uint, userId uint) (ContributionCounts, error)
+	VoteToOpenCapsule(userId uint, capsuleId uint) (OpenCapsuleResponse, error)
+	SetCapsuleRecurrence(capsuleId uint, recurrenceUnit *string, recurrenceInterval uint, namePattern string) error
+	CreateNextEdition(capsuleId uint) (uint, error)
+	RenewRecurringCapsules() (int64, error)
+	GetCapsuleSeries(userId uint, capsuleId uint) ([]CapsuleEdition, error)
 	SendReminderMail() error
 }
 
@@ -136,21 +412,67 @@ type GetCapsuleByIdResponse struct {
 	Songs           []Song           `json:"songs"`
 	QuestionAnswers []QuestionAnswer `json:"questionAnswers"`
 	Writings        []Writing        `json:"writings"`
+	Letters         []Letter         `json:"letters"` // the requester's own letters, and those to them that have unlocked
 	Photos          []Photo          `json:"photos"`
 	Audios          []Audio          `json:"audios"`
 	Doodles         []Doodle         `json:"doodles"`
 	MiscFiles       []MiscFile       `json:"miscFiles"`
+
+	HiddenContributions *ContributionCounts `json:"hiddenContributions"` // what other members added to an unopened surprise capsule
+}
+
+type ContributionCounts struct {
+	Songs           uint `json:"songs"`
+	QuestionAnswers uint `json:"questionAnswers"`
+	Writings        uint `json:"writings"`
+	Photos          uint `json:"photos"`
+	Audios          uint `json:"audios"`
+	Doodles         uint `json:"doodles"`
+	MiscFiles       uint `json:"miscFiles"`
+}
+
+// CapsulePreview is what a join link reveals about a capsule to someone who isn't a member
+type CapsulePreview struct {
+	Name             string `json:"name"`
+	Vessel           string `json:"vessel"`
+	OwnerName        string `json:"ownerName"`
+	RequiresApproval bool   `json:"requiresApproval"`
 }
 
 type CreateCapsulePayload struct {
-	Vessel string `json:"vessel" validate:"required,min=1,max=32"`
-	Public bool   `json:"public"`
+	Vessel      string `json:"vessel" validate:"required,min=1,max=32"`
+	Public      bool   `json:"public"`
+	MemberLimit uint   `json:"memberLimit" validate:"omitempty,min=1"`
+	Surprise    bool   `json:"surprise"`
+}
+
+type CreateCapsuleFromTemplatePayload struct {
+	TemplateID  uint `json:"templateId" validate:"required"`
+	Public      bool `json:"public"`
+	MemberLimit uint `json:"memberLimit" validate:"omitempty,min=1"`
+	Surprise    bool `json:"surprise"`
+}
+
+type SetCapsuleSurprisePayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+	Surprise  bool `json:"surprise"`
 }
 
 type JoinCapsulePayload struct {
 	Code string `json:"code" validate:"required,min=10,max=10"`
 }
 
+type RegenerateCapsuleCodePayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+}
+
+type UpdateCapsuleCodeSettingsPayload struct {
+	CapsuleID uint       `json:"capsuleId" validate:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"` // RFC 3339, omit for a code that never expires
+	MaxUses   *uint      `json:"maxUses" validate:"omitempty,min=1"`
+	Revoked   bool       `json:"revoked"`
+}
+
 type DeleteCapsulePayload struct {
 	CapsuleID uint `json:"capsuleId" validate:"required"`
 }
@@ -160,19 +482,225 @@ type NameCapsulePayload struct {
 	Name      string `json:"name" validate:"required,min=1,max=255"`
 }
 
+type SetOpenPolicyPayload struct {
+	CapsuleID  uint   `json:"capsuleId" validate:"required"`
+	OpenPolicy string `json:"openPolicy" validate:"required,oneof=owner any-member quorum"`
+	OpenQuorum uint   `json:"openQuorum"` // only used by the quorum policy, 0 for a majority of members
+}
+
+type SetCapsuleRecurrencePayload struct {
+	CapsuleID          uint   `json:"capsuleId" validate:"required"`
+	RecurrenceUnit     string `json:"recurrenceUnit" validate:"omitempty,oneof=day week month year"` // empty to stop recurring
+	RecurrenceInterval uint   `json:"recurrenceInterval" validate:"omitempty,min=1,max=100"`         // defaults to 1
+	NamePattern        string `json:"namePattern" validate:"max=255"`                                // eg. "New Year {year}"
+}
+
+// CapsuleEdition is one capsule in a recurring series
+type CapsuleEdition struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Edition    uint       `json:"edition"`
+	DateToOpen *time.Time `json:"dateToOpen"`
+	Sealed     string     `json:"sealed"`
+}
+
+type OpenCapsuleResponse struct {
+	Opened      bool `json:"opened"`
+	Votes       uint `json:"votes"`       // only counted under the quorum policy
+	VotesNeeded uint `json:"votesNeeded"` // only counted under the quorum policy
+}
+
 type SealCapsulePayload struct {
 	CapsuleID  uint   `json:"capsuleId" validate:"required"`
-	DateToOpen string `json:"dateToOpen" validate:"required"`
+	DateToOpen string `json:"dateToOpen" validate:"required"`         // RFC 3339, or a date to open at midnight in the timezone
+	Timezone   string `json:"timezone" validate:"omitempty,timezone"` // defaults to the capsule's timezone
+}
+
+type SetContributionDeadlinePayload struct {
+	CapsuleID            uint       `json:"capsuleId" validate:"required"`
+	ContributionDeadline *time.Time `json:"contributionDeadline"`                                     // RFC 3339, omit to remove the deadline
+	DateToOpen           string     `json:"dateToOpen" validate:"required_with=ContributionDeadline"` // opened on this date once sealed by the deadline
+	Timezone             string     `json:"timezone" validate:"omitempty,timezone"`
 }
 
 type MemberSealCapsulePayload struct {
 	CapsuleID uint `json:"capsuleId" validate:"required"`
 }
 
+type NudgeCapsuleMembersPayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+	UserID    uint `json:"userId"` // only nudge this member, everyone outstanding if left out
+}
+
+type SetCapsuleMemberRolePayload struct {
+	CapsuleID uint   `json:"capsuleId" validate:"required"`
+	UserID    uint   `json:"userId" validate:"required"`
+	Role      string `json:"role" validate:"required,oneof=editor contributor viewer"`
+}
+
+type LeaveCapsulePayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+}
+
+type RemoveCapsuleMemberPayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+	UserID    uint `json:"userId" validate:"required"`
+}
+
+type TransferCapsuleOwnershipPayload struct {
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+	UserID    uint `json:"userId" validate:"required"`
+}
+
 type OpenCapsulePayload struct {
 	CapsuleID uint `json:"capsuleId" validate:"required"`
 }
 
+// ====================================================================
+// Invite
+// ====================================================================
+
+type Invite struct {
+	ID          uint       `json:"id"`
+	CapsuleID   uint       `json:"capsuleId"`
+	InviterID   uint       `json:"inviterId"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+	Status      string     `json:"status"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RespondedAt *time.Time `json:"respondedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+
+	CapsuleName string `json:"capsuleName"`
+	InviterName string `json:"inviterName"`
+}
+
+type InviteStore interface {
+	GetInviteById(inviteId uint) (*Invite, error)
+	GetPendingInvites(email string, phone string) ([]Invite, error)
+	GetCapsuleInvites(capsuleId uint) ([]Invite, error)
+	CreateInvite(capsuleId uint, inviterId uint, email string, phone string) (uint, error)
+	UpdateInviteStatus(inviteId uint, status string) error
+}
+
+type CreateInvitePayload struct {
+	CapsuleID uint   `json:"capsuleId" validate:"required"`
+	Email     string `json:"email" validate:"required_without=Phone,omitempty,email"`
+	Phone     string `json:"phone" validate:"required_without=Email,omitempty,min=10,max=10"`
+}
+
+type RespondInvitePayload struct {
+	InviteID uint `json:"inviteId" validate:"required"`
+}
+
+// ====================================================================
+// JoinRequest
+// ====================================================================
+
+type JoinRequest struct {
+	ID          uint       `json:"id"`
+	CapsuleID   uint       `json:"capsuleId"`
+	UserID      uint       `json:"userId"`
+	Status      string     `json:"status"`
+	RespondedAt *time.Time `json:"respondedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+
+	CapsuleName string `json:"capsuleName"`
+	UserName    string `json:"userName"`
+}
+
+type JoinRequestStore interface {
+	GetJoinRequestById(joinRequestId uint) (*JoinRequest, error)
+	GetUserJoinRequests(userId uint) ([]JoinRequest, error)
+	GetCapsuleJoinRequests(capsuleId uint) ([]JoinRequest, error)
+	CreateJoinRequest(capsuleId uint, userId uint) (uint, error)
+	UpdateJoinRequestStatus(joinRequestId uint, status string) error
+}
+
+type RespondJoinRequestPayload struct {
+	JoinRequestID uint `json:"joinRequestId" validate:"required"`
+}
+
+// ====================================================================
+// Capsule Template
+// ====================================================================
+
+// CapsuleTemplate is a starting point for a new capsule, either one we provide or one a user saved
+type CapsuleTemplate struct {
+	ID            uint      `json:"id"`
+	UserID        *uint     `json:"userId"` // nil for the templates we provide
+	Name          string    `json:"name"`
+	Vessel        string    `json:"vessel"`
+	CapsuleName   string    `json:"capsuleName"`   // default name of capsules created from the template
+	OpenAfterDays *uint     `json:"openAfterDays"` // capsules open this many days after they're created, nil to pick when sealing
+	Prompts       []string  `json:"prompts"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type CapsuleTemplateStore interface {
+	GetCapsuleTemplates(userId uint) ([]CapsuleTemplate, error)
+	GetCapsuleTemplateById(userId uint, templateId uint) (*CapsuleTemplate, error)
+	CreateCapsuleTemplate(userId uint, name string, vessel string, capsuleName string, openAfterDays *uint, prompts []string) (uint, error)
+	DeleteCapsuleTemplate(userId uint, templateId uint) error
+}
+
+type CreateCapsuleTemplatePayload struct {
+	Name          string   `json:"name" validate:"required,max=255"`
+	Vessel        string   `json:"vessel" validate:"required,oneof=box suitcase 'guitar case' bottle shoe garbage"`
+	CapsuleName   string   `json:"capsuleName" validate:"required,max=255"`
+	OpenAfterDays *uint    `json:"openAfterDays" validate:"omitempty,min=1,max=36500"`
+	Prompts       []string `json:"prompts" validate:"max=20,dive,required,max=255"`
+}
+
+type DeleteCapsuleTemplatePayload struct {
+	TemplateID uint `json:"templateId" validate:"required"`
+}
+
+// ====================================================================
+// Gift
+// ====================================================================
+
+// GiftRecipient is someone outside the capsule who is sent a link to it once it opens
+type GiftRecipient struct {
+	ID          uint       `json:"id"`
+	CapsuleID   uint       `json:"capsuleId"`
+	SenderID    uint       `json:"senderId"`
+	Name        string     `json:"name"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+	UserID      *uint      `json:"userId"`
+	DeliveredAt *time.Time `json:"deliveredAt"`
+	ClaimedAt   *time.Time `json:"claimedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+
+	Link string `json:"link"` // so members can pass it on themselves, eg. to recipients with only a phone number
+}
+
+type GiftStore interface {
+	GetGiftRecipientById(recipientId uint) (*GiftRecipient, error)
+	GetCapsuleGiftRecipients(capsuleId uint) ([]GiftRecipient, error)
+	CreateGiftRecipient(capsuleId uint, senderId uint, name string, email string, phone string, userId *uint) (uint, error)
+	DeleteGiftRecipient(capsuleId uint, recipientId uint) error
+	ClaimGift(recipientId uint, userId uint) error
+	DeliverGifts() (int64, error)
+}
+
+type AddGiftRecipientPayload struct {
+	CapsuleID uint   `json:"capsuleId" validate:"required"`
+	Name      string `json:"name" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required_without=Phone,omitempty,email"`
+	Phone     string `json:"phone" validate:"required_without=Email,omitempty,min=10,max=10"`
+}
+
+type RemoveGiftRecipientPayload struct {
+	CapsuleID   uint `json:"capsuleId" validate:"required"`
+	RecipientID uint `json:"recipientId" validate:"required"`
+}
+
+type GiftTokenPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
 // ====================================================================
 // Song
 // ====================================================================
@@ -189,7 +717,7 @@ type Song struct {
 }
 
 type SongStore interface {
-	GetSongs(capsuleID uint) ([]Song, error)
+	GetSongs(capsuleID uint, userID uint) ([]Song, error)
 	CreateSong(userID uint, capsuleID uint, spotifyID string, name string, artistName string, albumArtURL string) (uint, error)
 	DeleteSong(userID uint, capsuleID uint, songID uint) error
 }
@@ -221,7 +749,7 @@ type QuestionAnswer struct {
 }
 
 type QuestionAnswerStore interface {
-	GetQuestionAnswers(capsuleID uint) ([]QuestionAnswer, error)
+	GetQuestionAnswers(capsuleID uint, userID uint) ([]QuestionAnswer, error)
 	CreateQuestionAnswer(userID, capsuleID uint, prompt string, answer string) (uint, error)
 	UpdateQuestionAnswer(userID, capsuleID uint, questionAnswerID uint, prompt string, answer string) error
 	DeleteQuestionAnswer(userID uint, capsuleID uint, questionAnswerID uint) error
@@ -258,7 +786,7 @@ type Writing struct {
 }
 
 type WritingStore interface {
-	GetWritings(capsuleID uint) ([]Writing, error)
+	GetWritings(capsuleID uint, userID uint) ([]Writing, error)
 	CreateWriting(userID, capsuleID uint, writing string) (uint, error)
 	UpdateWriting(userID, capsuleID, writingID uint, writing string) error
 	DeleteWriting(userID uint, capsuleID uint, writingID uint) error
@@ -280,6 +808,47 @@ type DeleteWritingPayload struct {
 	CapsuleID uint `json:"capsuleId" validate:"required"`
 }
 
+// ====================================================================
+// Letter
+// ====================================================================
+
+// Letter is written to one member of the capsule, who can only read it once it unlocks
+type Letter struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"userId"`
+	CapsuleID   uint      `json:"capsuleId"`
+	RecipientID uint      `json:"recipientId"`
+	UnlockAt    time.Time `json:"unlockAt"`
+	Letter      string    `json:"letter"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type LetterStore interface {
+	GetLetters(capsuleID uint, userID uint, now time.Time) ([]Letter, error)
+	CreateLetter(userID, capsuleID, recipientID uint, unlockAt time.Time, letter string) (uint, error)
+	UpdateLetter(userID, capsuleID, letterID uint, unlockAt time.Time, letter string) error
+	DeleteLetter(userID uint, capsuleID uint, letterID uint) error
+}
+
+type CreateLetterPayload struct {
+	CapsuleID   uint   `json:"capsuleId" validate:"required"`
+	RecipientID uint   `json:"recipientId" validate:"required"`
+	UnlockAt    string `json:"unlockAt" validate:"required"` // RFC 3339, or a date to unlock at midnight in the capsule's timezone
+	Letter      string `json:"letter" validate:"required,max=5000"`
+}
+
+type UpdateLetterPayload struct {
+	CapsuleID uint   `json:"capsuleId" validate:"required"`
+	LetterID  uint   `json:"letterId" validate:"required"`
+	UnlockAt  string `json:"unlockAt" validate:"required"`
+	Letter    string `json:"letter" validate:"required,max=5000"`
+}
+
+type DeleteLetterPayload struct {
+	LetterID  uint `json:"letterId" validate:"required"`
+	CapsuleID uint `json:"capsuleId" validate:"required"`
+}
+
 // ====================================================================
 // Photo
 // ====================================================================
@@ -294,7 +863,7 @@ type Photo struct {
 }
 
 type PhotoStore interface {
-	GetPhotos(capsuleID uint) ([]Photo, error)
+	GetPhotos(capsuleID uint, userID uint) ([]Photo, error)
 	CreatePhoto(userID uint, capsuleID uint, objectName string, fileURL string) (uint, error)
 	DeletePhoto(userID uint, capsuleID uint, photoID uint) (string, error)
 }
@@ -324,7 +893,7 @@ type Audio struct {
 }
 
 type AudioStore interface {
-	GetAudios(capsuleID uint) ([]Audio, error)
+	GetAudios(capsuleID uint, userID uint) ([]Audio, error)
 	CreateAudio(userID uint, capsuleID uint, objectName string, fileURL string) (uint, error)
 	DeleteAudio(userID uint, capsuleID uint, audioID uint) (string, error)
 }
@@ -354,7 +923,7 @@ type Doodle struct {
 }
 
 type DoodleStore interface {
-	GetDoodles(capsuleID uint) ([]Doodle, error)
+	GetDoodles(capsuleID uint, userID uint) ([]Doodle, error)
 	CreateDoodle(userID uint, capsuleID uint, objectName string, fileURL string) (uint, error)
 	DeleteDoodle(userID uint, capsuleID uint, doodleID uint) (string, error)
 }
@@ -384,7 +953,7 @@ type MiscFile struct {
 }
 
 type MiscFileStore interface {
-	GetMiscFiles(capsuleID uint) ([]MiscFile, error)
+	GetMiscFiles(capsuleID uint, userID uint) ([]MiscFile, error)
 	CreateMiscFile(userID uint, capsuleID uint, objectName string, fileURL string) (uint, error)
 	DeleteMiscFile(userID uint, capsuleID uint, miscFileID uint) (string, error)
 }
@@ -399,3 +968,21 @@ type DeleteMiscFilePayload struct {
 	CapsuleID  uint `json:"capsuleId" validate:"required"`
 	MiscFileID uint `json:"miscFileId" validate:"required"`
 }
+
+// ====================================================================
+// Clock
+// ====================================================================
+
+// Clock is where capsule lifecycle times come from, so they can be faked in development
+type Clock interface {
+	Now() time.Time
+}
+
+type AdvanceClockPayload struct {
+	Seconds int64 `json:"seconds" validate:"required,min=1"`
+}
+
+type ClockResponse struct {
+	Now             time.Time `json:"now"`
+	OffsetInSeconds int64     `json:"offsetInSeconds"`
+}
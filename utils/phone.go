@@ -0,0 +1,18 @@
+package utils
+
+import "strings"
+
+// NormalizePhone strips the formatting from a phone number and keeps its last 10 digits,
+// so "+1 (555) 123-4567" and "5551234567" are the same number
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
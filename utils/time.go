@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// LoadLocation looks up an IANA timezone, falling back to UTC for unknown or empty ones
+func LoadLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		return time.UTC
+	}
+	return loc
+}
+
+// ParseDateToOpen accepts an RFC 3339 timestamp, or a date which is taken as midnight in the timezone
+func ParseDateToOpen(value string, timezone string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, LoadLocation(timezone))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date to open the capsule")
+	}
+	return date.UTC(), nil
+}
+
+// LocalTime formats the time in the timezone as RFC 3339, empty if there's no time
+func LocalTime(t *time.Time, timezone string) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(LoadLocation(timezone)).Format(time.RFC3339)
+}
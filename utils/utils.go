@@ -2,9 +2,11 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/TenacityLabs/retrospect-backend/types"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -28,3 +30,15 @@ func WriteJSON(w http.ResponseWriter, status int, payload any) error {
 func WriteError(w http.ResponseWriter, status int, err error) {
 	WriteJSON(w, status, map[string]string{"error": err.Error()})
 }
+
+// AuthorizeErrorStatus picks the status code for an error from CapsuleStore.AuthorizeCapsule
+func AuthorizeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrCapsulePermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, types.ErrCapsuleNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}